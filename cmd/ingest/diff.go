@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+type diffCmd struct {
+	Old string `arg:"" help:"Directory of the previous bundle"`
+	New string `arg:"" optional:"" help:"Directory of the new bundle (default: generate from the Go project)"`
+}
+
+func (c *diffCmd) Run() error {
+	oldDir, err := filepath.Abs(c.Old)
+	if err != nil {
+		log.Fatalf("failed to resolve path %q: %v", c.Old, err)
+	}
+
+	old, err := spec.ReadBundle(oldDir)
+	if err != nil {
+		log.Fatalf("failed to read bundle %s: %v", c.Old, err)
+	}
+
+	var new *spec.Bundle
+	if c.New != "" {
+		if new, err = spec.ReadBundle(c.New); err != nil {
+			log.Fatalf("failed to read bundle %s: %v", c.New, err)
+		}
+	} else {
+		new, _ = generate(nil, oldDir)
+	}
+
+	changes := spec.Diff(old, new)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if spec.Breaking(changes) {
+		fmt.Fprintln(os.Stderr, "breaking changes found")
+		os.Exit(1)
+	}
+
+	return nil
+}
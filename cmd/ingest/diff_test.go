@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/spec"
+	"github.com/kure-sh/ingest-go/walk"
+)
+
+// Write a bundle of one group version, with a Spec of the given properties.
+func writeTestBundle(t *testing.T, props ...spec.Property) string {
+	t.Helper()
+
+	gv := &spec.APIGroupVersion{
+		API:     "widgets",
+		Group:   spec.APIGroupIdentifier{Name: "widgets.example.com"},
+		Version: "v1",
+		Definitions: []spec.Definition{{
+			DefinitionMeta: spec.DefinitionMeta{Name: "Spec"},
+			Value:          spec.Type{Variant: &spec.ObjectType{Properties: props}},
+		}},
+	}
+
+	bundle, err := spec.NewBundle([]*spec.APIGroupVersion{gv})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := walk.WriteBundle(bundle, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func testProperty(name string, required bool) spec.Property {
+	return spec.Property{
+		PropertyMeta: spec.PropertyMeta{DefinitionMeta: spec.DefinitionMeta{Name: name}, Required: required},
+		Value:        spec.Type{Variant: &spec.StringType{}},
+	}
+}
+
+func TestDiffCompatible(t *testing.T) {
+	old := writeTestBundle(t)
+	new := writeTestBundle(t, testProperty("name", false))
+
+	cmd := &diffCmd{Old: old, New: new}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffBreaking(t *testing.T) {
+	// a breaking change exits with a status of 1, so run it in a subprocess
+	if dirs := os.Getenv("INGEST_TEST_DIFF"); dirs != "" {
+		paths := filepath.SplitList(dirs)
+		(&diffCmd{Old: paths[0], New: paths[1]}).Run()
+		return
+	}
+
+	old := writeTestBundle(t, testProperty("name", false))
+	new := writeTestBundle(t, testProperty("name", true))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDiffBreaking$")
+	cmd.Env = append(os.Environ(), "INGEST_TEST_DIFF="+old+string(filepath.ListSeparator)+new)
+
+	out, err := cmd.Output()
+	exit, ok := err.(*exec.ExitError)
+	if !ok || exit.ExitCode() != 1 {
+		t.Fatalf("exited with %v, want status 1", err)
+	}
+
+	want := "BREAKING   changed   widgets.example.com/v1 Spec.name: now required\n"
+	if string(out) != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/kure-sh/ingest-go/spec"
+	"github.com/kure-sh/ingest-go/walk"
+)
+
+type generateCmd struct {
+	Output           string   `short:"o" default:"schema" help:"Directory to write generated schemas"`
+	CRDOutput        string   `name:"crd-output" help:"Directory to also write CustomResourceDefinition manifests"`
+	OpenAPIOutput    string   `name:"openapi-output" help:"Directory to also write an OpenAPI v3 document"`
+	JSONSchemaOutput string   `name:"jsonschema-output" help:"Directory to also write JSON Schema files for each resource"`
+	Check            bool     `help:"Compare the generated files with those on disk instead of writing, and fail if any differ"`
+	Managed          bool     `help:"Own the output directories: record the files written, remove those no longer generated, and replace each directory whole, so that an interrupted run keeps the previous output"`
+	Packages         []string `arg:"" optional:"" help:"Go packages to scan" name:"package"`
+}
+
+type outputFiles func(bundle *spec.Bundle, deps map[string]*spec.Bundle, out string) (walk.FileSet, error)
+
+func (c *generateCmd) Run() error {
+	output := absPath(c.Output)
+
+	// resolve paths before generate changes directory
+	outputs := []struct {
+		out   string
+		files outputFiles
+	}{
+		{output, func(bundle *spec.Bundle, _ map[string]*spec.Bundle, out string) (walk.FileSet, error) {
+			return walk.BundleFiles(bundle, out)
+		}},
+		{absPath(c.CRDOutput), walk.CRDFiles},
+		{absPath(c.OpenAPIOutput), walk.OpenAPIFiles},
+		{absPath(c.JSONSchemaOutput), walk.JSONSchemaFiles},
+	}
+
+	bundle, deps := generate(c.Packages, output)
+
+	fmt.Printf("API: %s\n", bundle.API.Name)
+
+	// gather the files of each directory, for managed output to own them all
+	var dirs []string
+	files := make(map[string]walk.FileSet)
+	for _, o := range outputs {
+		if o.out == "" {
+			continue
+		}
+
+		fs, err := o.files(bundle, deps, o.out)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+
+		if _, ok := files[o.out]; !ok {
+			dirs = append(dirs, o.out)
+		}
+		files[o.out] = append(files[o.out], fs...)
+	}
+
+	current := true
+	for _, dir := range dirs {
+		var ok bool
+		var err error
+
+		switch {
+		case c.Check && c.Managed:
+			ok, err = files[dir].CheckManaged(dir, os.Stdout)
+		case c.Check:
+			ok, err = files[dir].Check(os.Stdout)
+		case c.Managed:
+			ok, err = true, files[dir].WriteManaged(dir)
+		default:
+			ok, err = true, files[dir].Write()
+		}
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+
+		current = current && ok
+	}
+
+	if !current {
+		fmt.Fprintln(os.Stderr, "generated files are out of date")
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// Make an optional path absolute, exiting on error.
+func absPath(p string) string {
+	if p == "" {
+		return ""
+	}
+
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		log.Fatalf("failed to resolve output path %q: %v", p, err)
+	}
+
+	return abs
+}
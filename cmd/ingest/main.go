@@ -1,80 +1,142 @@
 package main
 
 import (
-	"fmt"
+	"errors"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
 
 	"github.com/alecthomas/kong"
 
 	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
 	"github.com/kure-sh/ingest-go/walk"
 )
 
 var cli struct {
-	Config   string   `short:"c" default:"kure.toml" help:"kure.toml configuration file"`
-	Cd       string   `short:"d" type:"path" help:"Change to this directory before starting"`
-	Output   string   `short:"o" default:"schema" help:"Directory to write generated schemas"`
-	Packages []string `arg:"" optional:"" help:"Go packages to scan" name:"package"`
+	Config string `short:"c" default:"kure.toml" help:"kure.toml configuration file"`
+	Cd     string `short:"d" type:"path" help:"Change to this directory before starting"`
+
+	Generate generateCmd `cmd:"" default:"withargs" help:"Generate Kure API definitions (the default command)"`
+	Diff     diffCmd     `cmd:"" help:"Compare two bundles and report breaking changes"`
 }
 
 func main() {
-	kong.Parse(&cli,
+	ctx := kong.Parse(&cli,
 		kong.Name("kure-ingest-go"),
 		kong.Description("Generate Kure API definitions from a Go project"),
 		kong.UsageOnError())
 
+	ctx.FatalIfErrorf(ctx.Run())
+}
+
+// Load the configuration and Go packages and generate a bundle, exiting on
+// any error. The previous bundle (if any) is read from the absolute path
+// previous, to compute the API version. The bundles of dependencies are
+// returned too.
+func generate(patterns []string, previous string) (*spec.Bundle, map[string]*spec.Bundle) {
 	conf, err := config.LoadConfig(cli.Config)
 	if err != nil {
 		log.Fatalf("failed to load kure.toml: %v", err)
 	}
 
-	output, err := filepath.Abs(cli.Output)
-	if err != nil {
-		log.Fatalf("failed to resolve output path %q: %v", cli.Output, err)
-	}
-
 	if cli.Cd != "" && cli.Cd != "." {
 		if err := os.Chdir(cli.Cd); err != nil {
 			log.Fatalf("failed to change working directory to %q: %v", cli.Cd, err)
 		}
 	}
 
-	patterns := cli.Packages
 	if len(patterns) == 0 {
 		if conf.Build == nil || len(conf.Build.Packages) == 0 {
-			log.Fatalf("no Go packages defined on command line or in %s", cli.Config)
+			log.Fatalf("no Go packages, CRD manifests or OpenAPI documents defined on command line or in %s", cli.Config)
 		}
 
 		patterns = conf.Build.Packages
 	}
 
-	packages, err := walk.LoadPackages(patterns...)
-	if err != nil {
-		log.Fatalf("failed to load packages: %v", err)
-	}
+	source := conf.Build.SourceKind()
 
 	local, err := walk.LoadGoModule()
-	if err != nil {
+	if errors.Is(err, fs.ErrNotExist) && source != config.BuildSourceGo {
+		local = &walk.LocalGoModule{}
+	} else if err != nil {
 		log.Fatalf("failed to load go.mod: %v", err)
 	}
 
+	bundles, err := walk.LoadDependencyBundles(conf)
+	if err != nil {
+		log.Fatalf("failed to load dependency bundle: %v", err)
+	}
+
+	if err := conf.ResolveBundles(bundles); err != nil {
+		log.Fatalf("failed to resolve dependency bundle: %v", err)
+	}
+
 	if err := conf.ResolveVersions(local.Dependencies); err != nil {
 		log.Fatalf("failed to resolve dependency version: %v", err)
 	}
 
-	walk.APIPackages(conf, local, packages)
-	gctx := walk.NewGeneratorContext(conf, packages)
+	var bundle *spec.Bundle
 
-	bundle, err := walk.GenerateBundle(gctx)
-	if err != nil {
-		log.Fatalf("error: %v", err)
+	switch source {
+	case config.BuildSourceGo:
+		packages, err := walk.LoadPackages(patterns...)
+		if err != nil {
+			log.Fatalf("failed to load packages: %v", err)
+		}
+
+		walk.APIPackages(conf, local, packages)
+		gctx := walk.NewGeneratorContext(conf, packages)
+		gctx.Bundles = bundles
+
+		bundle, err = walk.GenerateBundle(gctx)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+
+	case config.BuildSourceCRD:
+		crds, err := walk.LoadCRDs(patterns...)
+		if err != nil {
+			log.Fatalf("failed to load CRD manifests: %v", err)
+		}
+
+		gctx := walk.NewGeneratorContext(conf, nil)
+		gctx.Bundles = bundles
+
+		bundle, err = walk.GenerateCRDBundle(gctx, crds)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+
+	case config.BuildSourceOpenAPI:
+		docs, err := walk.LoadOpenAPI(patterns...)
+		if err != nil {
+			log.Fatalf("failed to load OpenAPI documents: %v", err)
+		}
+
+		gctx := walk.NewGeneratorContext(conf, nil)
+		gctx.Bundles = bundles
+
+		bundle, err = walk.GenerateOpenAPIBundle(gctx, docs)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+
+	default:
+		log.Fatalf("unknown build source %q", source)
 	}
 
-	fmt.Printf("API: %s\n", bundle.API.Name)
+	var prev *spec.Bundle
+	if conf.Version != nil && conf.Version.Bump {
+		prev, err = spec.ReadBundle(previous)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("failed to read previous bundle: %v", err)
+		}
+	}
 
-	if err := walk.WriteBundle(bundle, output); err != nil {
-		log.Fatalf("error: %v", err)
+	if bundle.API.Version, err = walk.APIVersion(conf, local, prev, bundle); err != nil {
+		log.Fatalf("failed to compute API version: %v", err)
 	}
+
+	return bundle, bundles
 }
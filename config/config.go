@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/kure-sh/ingest-go/spec"
@@ -11,30 +14,121 @@ import (
 )
 
 type Config struct {
-	Name         string       `toml:"name"`
-	Build        *Build       `toml:"build,omitempty"`
-	Exports      []Export     `toml:"export"`
-	Dependencies []Dependency `toml:"dependency"`
-	Externs      []Extern     `toml:"extern"`
+	Name         string        `toml:"name"`
+	Version      *Version      `toml:"version,omitempty"`
+	Build        *Build        `toml:"build,omitempty"`
+	Exports      []*Export     `toml:"export"`
+	Dependencies []Dependency  `toml:"dependency"`
+	Externs      []Extern      `toml:"extern"`
+	TypeMappings []TypeMapping `toml:"type-mapping"`
+
+	// Resolve k8s.io/api and apimachinery packages without [[extern]] entries
+	// (enabled by default).
+	BuiltinExterns *bool `toml:"builtin-externs,omitempty"`
 }
 
 type Build struct {
-	Source   string   `toml:"source"`
+	// What to ingest: "go" packages (the default), "crd" manifests or
+	// "openapi" documents
+	Source string `toml:"source"`
+	// Go package patterns, or the files and directories of CRD manifests or
+	// OpenAPI documents
 	Packages []string `toml:"packages,omitempty"`
 }
 
+const (
+	BuildSourceGo      = "go"
+	BuildSourceCRD     = "crd"
+	BuildSourceOpenAPI = "openapi"
+)
+
+func (b *Build) SourceKind() string {
+	if b == nil || b.Source == "" {
+		return BuildSourceGo
+	}
+
+	return b.Source
+}
+
+// How to compute the version of the API.
+type Version struct {
+	// An explicit version, used as the minimum version
+	Base string `toml:"base,omitempty"`
+	// Where to find the version: "config" (base), "git" (latest tag) or
+	// "module" (major version of the Go module path). By default, each is
+	// tried in turn.
+	Source string `toml:"source,omitempty"`
+	// Bump the version of the previous bundle according to the API changes
+	Bump bool `toml:"bump,omitempty"`
+}
+
+const (
+	VersionSourceConfig = "config"
+	VersionSourceGit    = "git"
+	VersionSourceModule = "module"
+)
+
 type Export struct {
 	Path    string `toml:"path"`
 	Module  string `toml:"module,omitempty"`
 	Group   string `toml:"group"`
 	Version string `toml:"version"`
 
+	// Definitions to export (all by default) and to leave out, by their
+	// original names: Go type names, CRD kinds or OpenAPI schema names
 	Include []string `toml:"include,omitempty"`
 	Exclude []string `toml:"exclude,omitempty"`
 
 	ExplicitNull bool   `toml:"explicit-null,omitempty"`
 	Prune        bool   `toml:"prune,omitempty"`
 	Merge        *Merge `toml:"merge,omitempty"`
+
+	// The order of definitions from Go packages: "name" (alphabetical, the
+	// default) or "source" (as declared, file by file)
+	Order string `toml:"order,omitempty"`
+
+	// Published names of definitions, applied after overrides and before
+	// merges (whose include and rename lists take the published names)
+	Renames []Rename `toml:"rename,omitempty"`
+	// Changes to definitions and their properties, by their original names
+	Overrides []Override `toml:"override,omitempty"`
+}
+
+// Publish a definition under another name (From is the original name).
+type Rename struct {
+	From string `toml:"from"`
+	To   string `toml:"to"`
+}
+
+// Change a definition, or one of its properties if set.
+type Override struct {
+	Definition string `toml:"definition"`
+	Property   string `toml:"property,omitempty"`
+
+	// A new name of the property
+	Name string `toml:"name,omitempty"`
+	// Leave the property out
+	Hide        bool    `toml:"hide,omitempty"`
+	Description *string `toml:"description,omitempty"`
+}
+
+const (
+	DefinitionOrderName   = "name"
+	DefinitionOrderSource = "source"
+)
+
+// Whether a definition is exported, according to the include and exclude
+// lists.
+func (e *Export) Includes(name string) bool {
+	if slices.Contains(e.Exclude, name) {
+		return false
+	}
+
+	if len(e.Include) > 0 {
+		return slices.Contains(e.Include, name)
+	}
+
+	return true
 }
 
 func (e *Export) Is(v *spec.APIGroupVersion) bool {
@@ -46,16 +140,38 @@ func (e *Export) Is(v *spec.APIGroupVersion) bool {
 	return v.Group.Name == e.Group && module == e.Module && v.Version == e.Version
 }
 
+// Merge the definitions of an export into another group-version, found by
+// module, version and (if ambiguous) group.
 type Merge struct {
-	Module  string   `toml:"module"`
-	Version string   `toml:"version,omitempty"`
+	Module  string `toml:"module"`
+	Group   string `toml:"group,omitempty"`
+	Version string `toml:"version,omitempty"`
+
+	// Definitions to merge (all by default), by their published names: after
+	// the export's renames
 	Include []string `toml:"include,omitempty"`
+
+	// New names of merged definitions, indexed by their published names in
+	// the export
+	Rename map[string]string `toml:"rename,omitempty"`
+	// What to do when a merged definition has the name of one in the target:
+	// "error" (the default), "keep" the target's definition, or "replace" it
+	Conflict string `toml:"conflict,omitempty"`
 }
 
+const (
+	MergeConflictError   = "error"
+	MergeConflictKeep    = "keep"
+	MergeConflictReplace = "replace"
+)
+
 type Dependency struct {
 	Name    string `toml:"name"`
 	Path    string `toml:"path,omitempty"`
 	Version string `toml:"version,omitempty"`
+	// A directory holding the generated bundle of the dependency, relative to
+	// kure.toml
+	Bundle string `toml:"bundle,omitempty"`
 }
 
 type Extern struct {
@@ -82,6 +198,20 @@ func LoadConfig(filename string) (*Config, error) {
 		return nil, err
 	}
 
+	base, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	for i, dep := range conf.Dependencies {
+		if dep.Bundle != "" && !filepath.IsAbs(dep.Bundle) {
+			conf.Dependencies[i].Bundle = filepath.Join(base, dep.Bundle)
+		}
+	}
+
+	if err := conf.checkTypeMappings(); err != nil {
+		return nil, err
+	}
+
 	return &conf, nil
 }
 
@@ -96,19 +226,42 @@ func (c *Config) Dependency(name string) *Dependency {
 }
 
 func (c *Config) Export(path string) *Export {
-	for i, export := range c.Exports {
+	for _, export := range c.Exports {
 		if export.Path == path {
-			return &c.Exports[i]
+			return export
 		}
 	}
 
 	return nil
 }
 
+// Declare an export which is not listed in kure.toml (e.g., one derived from
+// package markers), returning the stored export.
+func (c *Config) AddExport(export Export) *Export {
+	c.Exports = append(c.Exports, &export)
+	return &export
+}
+
 func (c *Config) ResolvePackage(path string) Package {
-	for i, export := range c.Exports {
+	if pkg := c.DeclaredPackage(path); pkg != nil {
+		return pkg
+	}
+
+	if c.builtinExterns() {
+		if extern := kubernetesExtern(path); extern != nil {
+			return extern
+		}
+	}
+
+	return nil
+}
+
+// Find the export or extern of a package declared in kure.toml (or added), not
+// considering builtin externs.
+func (c *Config) DeclaredPackage(path string) Package {
+	for _, export := range c.Exports {
 		if export.Path == path {
-			return &c.Exports[i]
+			return export
 		}
 	}
 
@@ -121,13 +274,57 @@ func (c *Config) ResolvePackage(path string) Package {
 	return nil
 }
 
+func (c *Config) builtinExterns() bool {
+	return c.BuiltinExterns == nil || *c.BuiltinExterns
+}
+
+// Fill in the versions of dependencies, and the groups and versions of
+// externs, from the bundles of dependencies (indexed by name).
+func (c *Config) ResolveBundles(bundles map[string]*spec.Bundle) error {
+	for i, dep := range c.Dependencies {
+		bundle := bundles[dep.Name]
+		if bundle == nil || dep.Version != "" || bundle.API.Version == "" {
+			continue
+		}
+
+		version, err := semver.NewVersion(bundle.API.Version)
+		if err != nil {
+			return fmt.Errorf("dependency %q: invalid bundle version %q: %w", dep.Name, bundle.API.Version, err)
+		}
+
+		c.Dependencies[i].Version = fmt.Sprintf("%d.%d", version.Major(), version.Minor())
+	}
+
+	for i, extern := range c.Externs {
+		bundle := bundles[extern.Package]
+		if bundle == nil || (extern.Group != "" && extern.Version != "") {
+			continue
+		}
+
+		if err := c.Externs[i].resolve(bundle); err != nil {
+			return fmt.Errorf("extern %s: %w", extern.Path, err)
+		}
+	}
+
+	return nil
+}
+
 func (c *Config) ResolveVersions(required map[string]*modfile.Require) error {
+	if c.builtinExterns() && c.Dependency(KubernetesDependency) == nil {
+		for _, path := range kubernetesModules {
+			if required[path] != nil {
+				c.Dependencies = append(c.Dependencies, Dependency{Name: KubernetesDependency, Path: path})
+				break
+			}
+		}
+	}
+
 	for i, dep := range c.Dependencies {
 		if dep.Version != "" {
 			continue
 		}
 		if dep.Path == "" {
-			return fmt.Errorf("dependency %q: no path or version set", dep.Name)
+			return fmt.Errorf("dependency %q: no path or version set (without a go.mod, set its version in kure.toml)", dep.Name)
 		}
 
 		req := required[dep.Path]
@@ -141,7 +338,7 @@ func (c *Config) ResolveVersions(required map[string]*modfile.Require) error {
 		}
 
 		major, minor := version.Major(), version.Minor()
-		if dep.Name == "kubernetes" && major == 0 {
+		if dep.Name == KubernetesDependency && major == 0 {
 			major = 1
 		}
 
@@ -171,3 +368,43 @@ func (e *Extern) Export() *Export {
 		Version: e.Version,
 	}
 }
+
+// Find the group and version of the extern in its package's bundle: the group
+// must be the only one with the extern's module, and the version defaults to
+// the last element of the Go package path, or the preferred version.
+func (e *Extern) resolve(bundle *spec.Bundle) error {
+	var group *spec.APIGroup
+
+	for _, g := range bundle.Groups {
+		var module string
+		if g.Module != nil {
+			module = *g.Module
+		}
+
+		if module != e.Module || (e.Group != "" && g.Name != e.Group) {
+			continue
+		}
+		if group != nil {
+			return fmt.Errorf("ambiguous group in module %q of %s", e.Module, e.Package)
+		}
+
+		group = g
+	}
+	if group == nil {
+		return fmt.Errorf("no group in module %q of %s", e.Module, e.Package)
+	}
+
+	e.Group = group.Name
+
+	if e.Version == "" {
+		if version := path.Base(e.Path); slices.Contains(group.Versions, version) {
+			e.Version = version
+		} else if group.PreferredVersion != nil {
+			e.Version = *group.PreferredVersion
+		} else {
+			return fmt.Errorf("no version of group %s matches", group.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The name of the dependency on the builtin Kubernetes API's.
+const KubernetesDependency = "kubernetes"
+
+// Go modules which provide the builtin Kubernetes API's, in order of
+// preference when resolving the version of the kubernetes dependency.
+var kubernetesModules = []string{"k8s.io/api", "k8s.io/apimachinery"}
+
+// API group names of the k8s.io/api packages, by package (kure module) name.
+var kubernetesGroups = map[string]string{
+	"admission":             "admission.k8s.io",
+	"admissionregistration": "admissionregistration.k8s.io",
+	"apidiscovery":          "apidiscovery.k8s.io",
+	"apiserverinternal":     "internal.apiserver.k8s.io",
+	"apps":                  "apps",
+	"authentication":        "authentication.k8s.io",
+	"authorization":         "authorization.k8s.io",
+	"autoscaling":           "autoscaling",
+	"batch":                 "batch",
+	"certificates":          "certificates.k8s.io",
+	"coordination":          "coordination.k8s.io",
+	"core":                  "core",
+	"discovery":             "discovery.k8s.io",
+	"events":                "events.k8s.io",
+	"extensions":            "extensions",
+	"flowcontrol":           "flowcontrol.apiserver.k8s.io",
+	"imagepolicy":           "imagepolicy.k8s.io",
+	"networking":            "networking.k8s.io",
+	"node":                  "node.k8s.io",
+	"policy":                "policy",
+	"rbac":                  "rbac.authorization.k8s.io",
+	"resource":              "resource.k8s.io",
+	"scheduling":            "scheduling.k8s.io",
+	"storage":               "storage.k8s.io",
+	"storagemigration":      "storagemigration.k8s.io",
+}
+
+var kubernetesVersion = regexp.MustCompile(`^v\d+((alpha|beta)\d+)?$`)
+
+// Find the builtin extern for a k8s.io/api/<group>/<version> or
+// k8s.io/apimachinery/pkg/apis/meta/<version> package.
+func kubernetesExtern(path string) *Extern {
+	var module, group, version string
+
+	if rest, ok := strings.CutPrefix(path, "k8s.io/api/"); ok {
+		module, version, _ = strings.Cut(rest, "/")
+		group = kubernetesGroups[module]
+	} else if rest, ok := strings.CutPrefix(path, "k8s.io/apimachinery/pkg/apis/meta/"); ok {
+		module, group, version = "meta", "meta", rest
+	}
+
+	if group == "" || !kubernetesVersion.MatchString(version) {
+		return nil
+	}
+
+	return &Extern{
+		Path:    path,
+		Package: KubernetesDependency,
+		Module:  module,
+		Group:   group,
+		Version: version,
+	}
+}
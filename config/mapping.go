@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// Map a Go type (or the OpenAPI schema of one) to a kure type, in place of a
+// reference to it.
+type TypeMapping struct {
+	Path string `toml:"path"`
+	Name string `toml:"name"`
+	// The kure type, as written in a bundle (e.g., {type = "string", format =
+	// "uri"})
+	Type map[string]any `toml:"type"`
+
+	// The type encoded as JSON, once checked to be a kure type
+	data []byte
+}
+
+// Types mapped unless overridden by [[type-mapping]] entries.
+var builtinTypeMappings = []TypeMapping{
+	{Path: "k8s.io/apimachinery/pkg/runtime", Name: "Object", Type: map[string]any{"type": "unknown"}},
+	{Path: "k8s.io/apimachinery/pkg/runtime", Name: "RawExtension", Type: map[string]any{"type": "unknown"}},
+	{
+		Path: "k8s.io/apimachinery/pkg/util/intstr",
+		Name: "IntOrString",
+		Type: map[string]any{
+			"type": "union",
+			"values": []any{
+				map[string]any{"type": "integer", "size": 32},
+				map[string]any{"type": "string"},
+			},
+		},
+	},
+	{Path: "time", Name: "Duration", Type: map[string]any{"type": "string", "format": "duration"}},
+	{Path: "time", Name: "Time", Type: map[string]any{"type": "string", "format": "date-time"}},
+}
+
+// Types mapped to those of the kubernetes dependency, like builtin externs
+// (and only when they are enabled).
+var kubernetesTypeMappings = []TypeMapping{
+	{Path: "k8s.io/apimachinery/pkg/api/resource", Name: "Quantity", Type: quantityReference},
+	{Path: "k8s.io/apimachinery/pkg/api/resource", Name: "QuantityValue", Type: quantityReference},
+}
+
+// Quantity is defined by the meta module of the Kubernetes bundle.
+var quantityReference = map[string]any{
+	"type": "reference",
+	"target": map[string]any{
+		"scope": map[string]any{
+			"package": KubernetesDependency,
+			"group":   map[string]any{"module": "meta", "name": "meta"},
+			"version": "v1",
+		},
+		"name": "Quantity",
+	},
+}
+
+func init() {
+	for _, mappings := range [][]TypeMapping{builtinTypeMappings, kubernetesTypeMappings} {
+		for i := range mappings {
+			if err := mappings[i].check(); err != nil {
+				panic(fmt.Sprintf("builtin type mapping of %s.%s: %v", mappings[i].Path, mappings[i].Name, err))
+			}
+		}
+	}
+}
+
+// Check that each [[type-mapping]] is a kure type.
+func (c *Config) checkTypeMappings() error {
+	var errs []error
+
+	for i := range c.TypeMappings {
+		m := &c.TypeMappings[i]
+		if err := m.check(); err != nil {
+			errs = append(errs, fmt.Errorf("type mapping of %s.%s: %w", m.Path, m.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Find the type a Go type is mapped to, by kure.toml or else by default. Each
+// call returns a new type, which the caller may modify.
+func (c *Config) MapType(path, name string) (*spec.Type, error) {
+	all := [][]TypeMapping{c.TypeMappings, builtinTypeMappings}
+	if c.builtinExterns() {
+		all = append(all, kubernetesTypeMappings)
+	}
+
+	for _, mappings := range all {
+		for i := range mappings {
+			if m := &mappings[i]; m.Path == path && m.Name == name {
+				t, err := m.SpecType()
+				if err != nil {
+					return nil, fmt.Errorf("type mapping of %s.%s: %w", path, name, err)
+				}
+				return t, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Decode the kure type, checking it first if the mapping was not loaded with
+// the config.
+func (m *TypeMapping) SpecType() (*spec.Type, error) {
+	if m.data == nil {
+		if err := m.check(); err != nil {
+			return nil, err
+		}
+	}
+
+	var t spec.Type
+	if err := json.Unmarshal(m.data, &t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (m *TypeMapping) check() error {
+	data, err := json.Marshal(m.Type)
+	if err != nil {
+		return err
+	}
+
+	var t spec.Type
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	m.data = data
+	return nil
+}
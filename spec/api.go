@@ -2,6 +2,7 @@ package spec
 
 import (
 	"fmt"
+	"sort"
 )
 
 type Bundle struct {
@@ -60,11 +61,35 @@ func NewBundle(gvs []*APIGroupVersion) (*Bundle, error) {
 		}
 	}
 
+	// order groups by identifier, versions by priority and dependencies by
+	// package, regardless of the order they were generated in
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].APIGroupIdentifier.String() < groups[j].APIGroupIdentifier.String()
+	})
+	sort.SliceStable(deps, func(i, j int) bool {
+		return deps[i].Package < deps[j].Package
+	})
+
+	rank := make(map[string]int, len(groups))
 	var groupIds []APIGroupIdentifier
-	for _, g := range groups {
+	for i, g := range groups {
+		sort.SliceStable(g.Versions, func(i, j int) bool {
+			return compareVersions(g.Versions[i], g.Versions[j]) > 0
+		})
+		g.PreferredVersion = preferredVersion(g, gvs)
 		groupIds = append(groupIds, g.APIGroupIdentifier)
+		rank[g.APIGroupIdentifier.String()] = i
 	}
 
+	versions := append([]*APIGroupVersion(nil), gvs...)
+	sort.SliceStable(versions, func(i, j int) bool {
+		gi, gj := rank[versions[i].Group.String()], rank[versions[j].Group.String()]
+		if gi != gj {
+			return gi < gj
+		}
+		return compareVersions(versions[i].Version, versions[j].Version) > 0
+	})
+
 	api := API{
 		APIVersion:   APIVersion,
 		Kind:         "API",
@@ -76,10 +101,29 @@ func NewBundle(gvs []*APIGroupVersion) (*Bundle, error) {
 	return &Bundle{
 		API:      api,
 		Groups:   groups,
-		Versions: gvs,
+		Versions: versions,
 	}, nil
 }
 
+// Choose the served version of a group with the highest Kubernetes version
+// priority (e.g., v2 > v1 > v1beta2 > v1alpha1).
+func preferredVersion(group *APIGroup, gvs []*APIGroupVersion) *string {
+	var preferred *string
+
+	for _, gv := range gvs {
+		if !gv.Group.Same(group.APIGroupIdentifier) || !gv.Served() {
+			continue
+		}
+
+		if preferred == nil || compareVersions(gv.Version, *preferred) > 0 {
+			v := gv.Version
+			preferred = &v
+		}
+	}
+
+	return preferred
+}
+
 const APIVersion = "spec.kure.sh/v1alpha1"
 
 type API struct {
@@ -120,6 +164,14 @@ func (i APIGroupIdentifier) Same(o APIGroupIdentifier) bool {
 	return sameModule && i.Name == o.Name
 }
 
+func (i APIGroupIdentifier) String() string {
+	if i.Module != nil {
+		return *i.Module + "/" + i.Name
+	}
+
+	return i.Name
+}
+
 type APIGroupVersion struct {
 	APIVersion string `json:"apiVersion,omitempty"`
 	Kind       string `json:"kind,omitempty"`
@@ -133,6 +185,24 @@ type APIGroupVersion struct {
 	Definitions []Definition `json:"definitions"`
 }
 
+// Whether the API server serves this version: true unless every resource in
+// it is marked as unserved.
+func (gv *APIGroupVersion) Served() bool {
+	served := true
+
+	for _, def := range gv.Definitions {
+		if res, ok := def.Value.Variant.(*ResourceType); ok {
+			if !res.Metadata.Unserved {
+				return true
+			}
+
+			served = false
+		}
+	}
+
+	return served
+}
+
 type APIDependency struct {
 	Package string `json:"package"`
 	Version string `json:"version"`
@@ -0,0 +1,304 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+type Change struct {
+	// The changed group-version, definition or property, e.g.
+	// "apps/v1 Deployment.spec.replicas"
+	Path    string     `json:"path"`
+	Kind    ChangeKind `json:"kind"`
+	Message string     `json:"message"`
+	// Whether existing clients or objects may be incompatible with the change
+	Breaking bool `json:"breaking"`
+}
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+	// Documentation only: descriptions or deprecation
+	ChangeDescribed ChangeKind = "described"
+)
+
+func (c Change) String() string {
+	compat := "compatible"
+	if c.Breaking {
+		compat = "BREAKING"
+	}
+
+	return fmt.Sprintf("%-10s %-9s %s: %s", compat, c.Kind, c.Path, c.Message)
+}
+
+// Compare two bundles, listing the changes from old to new.
+//
+// A change is breaking if objects written by clients of the old API may be
+// rejected by the new one: removals, new requirements and narrower types are
+// breaking, while additions and relaxed requirements (no longer required, now
+// nullable) are compatible.
+func Diff(old, new *Bundle) []Change {
+	d := &differ{}
+
+	oldVersions := indexVersions(old)
+	newVersions := indexVersions(new)
+
+	for _, key := range sortedKeys(oldVersions, newVersions) {
+		ov, nv := oldVersions[key], newVersions[key]
+
+		switch {
+		case nv == nil:
+			d.add(key, ChangeRemoved, true, "group version removed")
+		case ov == nil:
+			d.add(key, ChangeAdded, false, "group version added")
+		default:
+			d.definitions(key, ov, nv)
+		}
+	}
+
+	return d.changes
+}
+
+// Whether any of the changes is breaking.
+func Breaking(changes []Change) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+
+	return false
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(path string, kind ChangeKind, breaking bool, format string, args ...any) {
+	d.changes = append(d.changes, Change{
+		Path:     path,
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, args...),
+		Breaking: breaking,
+	})
+}
+
+func (d *differ) definitions(gvPath string, old, new *APIGroupVersion) {
+	oldDefs := indexDefinitions(old)
+	newDefs := indexDefinitions(new)
+
+	for _, name := range sortedKeys(oldDefs, newDefs) {
+		od, nd := oldDefs[name], newDefs[name]
+		path := gvPath + " " + name
+
+		switch {
+		case nd == nil:
+			d.add(path, ChangeRemoved, true, "definition removed")
+		case od == nil:
+			d.add(path, ChangeAdded, false, "definition added")
+		default:
+			d.meta(path, od.DefinitionMeta, nd.DefinitionMeta)
+			d.types(path, od.Value, nd.Value)
+		}
+	}
+}
+
+func (d *differ) meta(path string, old, new DefinitionMeta) {
+	if old.Description != new.Description {
+		d.add(path, ChangeDescribed, false, "description changed")
+	}
+	if !old.Deprecated && new.Deprecated {
+		d.add(path, ChangeDescribed, false, "deprecated")
+	}
+}
+
+func (d *differ) types(path string, old, new Type) {
+	if old.Variant.Variant() != new.Variant.Variant() {
+		if o, ok := old.Variant.(*OptionalType); ok && o.Value.Variant.Variant() == new.Variant.Variant() {
+			d.add(path, ChangeChanged, true, "no longer nullable")
+			d.types(path, o.Value, new)
+		} else if n, ok := new.Variant.(*OptionalType); ok && n.Value.Variant.Variant() == old.Variant.Variant() {
+			d.add(path, ChangeChanged, false, "now nullable")
+			d.types(path, old, n.Value)
+		} else {
+			d.add(path, ChangeChanged, true, "type changed from %s to %s", old.Variant.Variant(), new.Variant.Variant())
+		}
+		return
+	}
+
+	switch o := old.Variant.(type) {
+	case *StringType:
+		n := new.Variant.(*StringType)
+		d.enum(path, o.Enum, n.Enum)
+		if o.Format != n.Format {
+			d.add(path, ChangeChanged, true, "format changed from %q to %q", o.Format, n.Format)
+		}
+
+	case *IntegerType:
+		n := new.Variant.(*IntegerType)
+		if o.Size != n.Size {
+			d.add(path, ChangeChanged, sizeNarrowed(o.Size, n.Size), "size changed from %d to %d", o.Size, n.Size)
+		}
+
+	case *FloatType:
+		n := new.Variant.(*FloatType)
+		if o.Size != n.Size {
+			d.add(path, ChangeChanged, sizeNarrowed(o.Size, n.Size), "size changed from %d to %d", o.Size, n.Size)
+		}
+
+	case *ObjectType:
+		n := new.Variant.(*ObjectType)
+		oldInherit, newInherit := typeNames(o.Inherit), typeNames(n.Inherit)
+		if !slices.Equal(oldInherit, newInherit) {
+			d.add(path, ChangeChanged, true, "inherited types changed from %v to %v", oldInherit, newInherit)
+		}
+		d.properties(path, o.Properties, n.Properties)
+
+	case *ResourceType:
+		n := new.Variant.(*ResourceType)
+		om, nm := o.Metadata, n.Metadata
+		if om.Name != nm.Name || om.Kind != nm.Kind {
+			d.add(path, ChangeChanged, true, "resource renamed from %s (%s) to %s (%s)", om.Name, om.Kind, nm.Name, nm.Kind)
+		}
+		if om.Scope != nm.Scope {
+			d.add(path, ChangeChanged, true, "scope changed from %s to %s", om.Scope, nm.Scope)
+		}
+		d.properties(path, o.Properties, n.Properties)
+
+	case *ArrayType:
+		d.types(path+"[]", o.Values, new.Variant.(*ArrayType).Values)
+
+	case *MapType:
+		d.types(path+"{}", o.Values, new.Variant.(*MapType).Values)
+
+	case *OptionalType:
+		d.types(path, o.Value, new.Variant.(*OptionalType).Value)
+
+	case *UnionType:
+		oldValues, newValues := typeNames(o.Values), typeNames(new.Variant.(*UnionType).Values)
+		if !slices.Equal(oldValues, newValues) {
+			d.add(path, ChangeChanged, true, "union changed from %v to %v", oldValues, newValues)
+		}
+
+	case *ReferenceType:
+		oldTarget, newTarget := o.Target.String(), new.Variant.(*ReferenceType).Target.String()
+		if oldTarget != newTarget {
+			d.add(path, ChangeChanged, true, "reference changed from %s to %s", oldTarget, newTarget)
+		}
+	}
+}
+
+func (d *differ) properties(path string, old, new []Property) {
+	oldProps := make(map[string]*Property, len(old))
+	for i := range old {
+		oldProps[old[i].Name] = &old[i]
+	}
+	newProps := make(map[string]*Property, len(new))
+	for i := range new {
+		newProps[new[i].Name] = &new[i]
+	}
+
+	for _, name := range sortedKeys(oldProps, newProps) {
+		op, np := oldProps[name], newProps[name]
+		ppath := path + "." + name
+
+		switch {
+		case np == nil:
+			d.add(ppath, ChangeRemoved, true, "property removed")
+		case op == nil:
+			if np.Required {
+				d.add(ppath, ChangeAdded, true, "required property added")
+			} else {
+				d.add(ppath, ChangeAdded, false, "optional property added")
+			}
+		default:
+			if op.Required && !np.Required {
+				d.add(ppath, ChangeChanged, false, "no longer required")
+			} else if !op.Required && np.Required {
+				d.add(ppath, ChangeChanged, true, "now required")
+			}
+			d.meta(ppath, op.DefinitionMeta, np.DefinitionMeta)
+			d.types(ppath, op.Value, np.Value)
+		}
+	}
+}
+
+func (d *differ) enum(path string, old, new []string) {
+	switch {
+	case len(old) == 0 && len(new) > 0:
+		d.add(path, ChangeChanged, true, "restricted to enum %v", new)
+	case len(old) > 0 && len(new) == 0:
+		d.add(path, ChangeChanged, false, "no longer an enum")
+	default:
+		for _, value := range old {
+			if !slices.Contains(new, value) {
+				d.add(path, ChangeRemoved, true, "enum value %q removed", value)
+			}
+		}
+		for _, value := range new {
+			if !slices.Contains(old, value) {
+				d.add(path, ChangeAdded, false, "enum value %q added", value)
+			}
+		}
+	}
+}
+
+// A narrower number is breaking; 0 means unspecified (i.e., the widest).
+func sizeNarrowed(old, new int) bool {
+	return old == 0 || (new != 0 && new < old)
+}
+
+// Describe each type briefly, by variant or reference target.
+func typeNames(ts []Type) []string {
+	names := make([]string, len(ts))
+
+	for i, t := range ts {
+		if ref, ok := t.Variant.(*ReferenceType); ok {
+			names[i] = ref.Target.String()
+		} else {
+			names[i] = t.Variant.Variant()
+		}
+	}
+
+	return names
+}
+
+func indexVersions(b *Bundle) map[string]*APIGroupVersion {
+	index := make(map[string]*APIGroupVersion, len(b.Versions))
+
+	for _, gv := range b.Versions {
+		index[gv.Group.String()+"/"+gv.Version] = gv
+	}
+
+	return index
+}
+
+func indexDefinitions(gv *APIGroupVersion) map[string]*Definition {
+	index := make(map[string]*Definition, len(gv.Definitions))
+
+	for i := range gv.Definitions {
+		index[gv.Definitions[i].Name] = &gv.Definitions[i]
+	}
+
+	return index
+}
+
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func property(name string, required bool, t TypeVariant) Property {
+	return Property{
+		PropertyMeta: PropertyMeta{DefinitionMeta: DefinitionMeta{Name: name}, Required: required},
+		Value:        Type{Variant: t},
+	}
+}
+
+func object(name string, props ...Property) Definition {
+	return Definition{
+		DefinitionMeta: DefinitionMeta{Name: name},
+		Value:          Type{Variant: &ObjectType{Properties: props}},
+	}
+}
+
+func resource(name, kind string, props ...Property) Definition {
+	return Definition{
+		DefinitionMeta: DefinitionMeta{Name: kind},
+		Value: Type{Variant: &ResourceType{
+			Properties: props,
+			Metadata:   ResourceMeta{Name: name, Kind: kind, Scope: "Namespaced"},
+		}},
+	}
+}
+
+func bundle(versions ...*APIGroupVersion) *Bundle {
+	return &Bundle{Versions: versions}
+}
+
+func groupVersion(group, version string, defs ...Definition) *APIGroupVersion {
+	return &APIGroupVersion{Group: APIGroupIdentifier{Name: group}, Version: version, Definitions: defs}
+}
+
+func TestDiff(t *testing.T) {
+	str := func() TypeVariant { return &StringType{} }
+	optional := func(v TypeVariant) TypeVariant { return &OptionalType{Value: Type{Variant: v}} }
+
+	tests := []struct {
+		name string
+		old  *Bundle
+		new  *Bundle
+		want []Change
+	}{
+		{
+			name: "unchanged",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("name", true, str())))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("name", true, str())))),
+			want: nil,
+		},
+		{
+			name: "optional property added",
+			old:  bundle(groupVersion("apps", "v1", object("Spec"))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, str())))),
+			want: []Change{{Path: "apps/v1 Spec.name", Kind: ChangeAdded, Message: "optional property added"}},
+		},
+		{
+			name: "required property added",
+			old:  bundle(groupVersion("apps", "v1", object("Spec"))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("name", true, str())))),
+			want: []Change{{Path: "apps/v1 Spec.name", Kind: ChangeAdded, Message: "required property added", Breaking: true}},
+		},
+		{
+			name: "property removed",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, str())))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec"))),
+			want: []Change{{Path: "apps/v1 Spec.name", Kind: ChangeRemoved, Message: "property removed", Breaking: true}},
+		},
+		{
+			name: "now required",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, str())))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("name", true, str())))),
+			want: []Change{{Path: "apps/v1 Spec.name", Kind: ChangeChanged, Message: "now required", Breaking: true}},
+		},
+		{
+			name: "no longer required",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("name", true, str())))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, str())))),
+			want: []Change{{Path: "apps/v1 Spec.name", Kind: ChangeChanged, Message: "no longer required"}},
+		},
+		{
+			name: "type changed",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("size", false, str())))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("size", false, &IntegerType{})))),
+			want: []Change{{Path: "apps/v1 Spec.size", Kind: ChangeChanged, Message: "type changed from string to integer", Breaking: true}},
+		},
+		{
+			name: "array items changed",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("tags", false, &ArrayType{Values: Type{Variant: str()}})))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("tags", false, &ArrayType{Values: Type{Variant: &BooleanType{}}})))),
+			want: []Change{{Path: "apps/v1 Spec.tags[]", Kind: ChangeChanged, Message: "type changed from string to boolean", Breaking: true}},
+		},
+		{
+			name: "now nullable",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, str())))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, optional(str()))))),
+			want: []Change{{Path: "apps/v1 Spec.name", Kind: ChangeChanged, Message: "now nullable"}},
+		},
+		{
+			name: "no longer nullable",
+			old:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, optional(str()))))),
+			new:  bundle(groupVersion("apps", "v1", object("Spec", property("name", false, str())))),
+			want: []Change{{Path: "apps/v1 Spec.name", Kind: ChangeChanged, Message: "no longer nullable", Breaking: true}},
+		},
+		{
+			name: "version removed",
+			old:  bundle(groupVersion("apps", "v1"), groupVersion("apps", "v1beta1")),
+			new:  bundle(groupVersion("apps", "v1")),
+			want: []Change{{Path: "apps/v1beta1", Kind: ChangeRemoved, Message: "group version removed", Breaking: true}},
+		},
+		{
+			name: "version added",
+			old:  bundle(groupVersion("apps", "v1")),
+			new:  bundle(groupVersion("apps", "v1"), groupVersion("apps", "v2")),
+			want: []Change{{Path: "apps/v2", Kind: ChangeAdded, Message: "group version added"}},
+		},
+		{
+			name: "resource removed",
+			old:  bundle(groupVersion("apps", "v1", resource("deployments", "Deployment"), resource("replicasets", "ReplicaSet"))),
+			new:  bundle(groupVersion("apps", "v1", resource("deployments", "Deployment"))),
+			want: []Change{{Path: "apps/v1 ReplicaSet", Kind: ChangeRemoved, Message: "definition removed", Breaking: true}},
+		},
+		{
+			name: "resource scope changed",
+			old:  bundle(groupVersion("apps", "v1", resource("deployments", "Deployment"))),
+			new: bundle(groupVersion("apps", "v1", Definition{
+				DefinitionMeta: DefinitionMeta{Name: "Deployment"},
+				Value:          Type{Variant: &ResourceType{Metadata: ResourceMeta{Name: "deployments", Kind: "Deployment", Scope: "Cluster"}}},
+			})),
+			want: []Change{{Path: "apps/v1 Deployment", Kind: ChangeChanged, Message: "scope changed from Namespaced to Cluster", Breaking: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.old, tt.new)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
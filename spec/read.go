@@ -0,0 +1,63 @@
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// Read a bundle from the directory layout written by walk.WriteBundle:
+// index.json, then group.json and <version>.json in each group's module.
+func ReadBundle(dir string) (*Bundle, error) {
+	return ReadBundleFS(os.DirFS(dir))
+}
+
+// Read a bundle from the root of fsys.
+func ReadBundleFS(fsys fs.FS) (*Bundle, error) {
+	var bundle Bundle
+
+	if err := readJSON(fsys, "index.json", &bundle.API); err != nil {
+		return nil, err
+	}
+
+	for _, id := range bundle.API.Groups {
+		base := "."
+		if id.Module != nil {
+			base = *id.Module
+		}
+
+		var group APIGroup
+		if err := readJSON(fsys, path.Join(base, "group.json"), &group); err != nil {
+			return nil, err
+		}
+		if !group.APIGroupIdentifier.Same(id) {
+			return nil, fmt.Errorf("%s: group %s does not match index", path.Join(base, "group.json"), group.Name)
+		}
+		bundle.Groups = append(bundle.Groups, &group)
+
+		for _, version := range group.Versions {
+			var gv APIGroupVersion
+			if err := readJSON(fsys, path.Join(base, version+".json"), &gv); err != nil {
+				return nil, err
+			}
+			bundle.Versions = append(bundle.Versions, &gv)
+		}
+	}
+
+	return &bundle, nil
+}
+
+func readJSON(fsys fs.FS, name string, v any) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
@@ -1,5 +1,10 @@
 package spec
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type ResourceType struct {
 	Properties []Property   `json:"properties"`
 	Metadata   ResourceMeta `json:"metadata"`
@@ -15,11 +20,78 @@ type ResourceMeta struct {
 	Kind         string       `json:"kind"`
 	Scope        string       `json:"scope"`
 	Subresources Subresources `json:"subresources,omitempty"`
+	// The verbs supported by the resource (see walk.standardVerbs)
+	Verbs []string `json:"verbs,omitempty"`
+
+	// Whether this version is the storage version of the resource
+	Storage bool `json:"storage,omitempty"`
+	// Whether this version is not served by the API server
+	Unserved           bool   `json:"unserved,omitempty"`
+	DeprecatedVersion  bool   `json:"deprecatedVersion,omitempty"`
+	DeprecationWarning string `json:"deprecationWarning,omitempty"`
+
+	ShortNames     []string        `json:"shortNames,omitempty"`
+	Categories     []string        `json:"categories,omitempty"`
+	PrinterColumns []PrinterColumn `json:"printerColumns,omitempty"`
+}
+
+// An additional column shown by `kubectl get`.
+type PrinterColumn struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	JSONPath    string `json:"jsonPath"`
+	Description string `json:"description,omitempty"`
+	Priority    int32  `json:"priority,omitempty"`
+	Format      string `json:"format,omitempty"`
 }
 
 type Subresources struct {
-	Status bool `json:"status,omitempty"`
-	Scale  bool `json:"scale,omitempty"`
+	Status bool              `json:"status,omitempty"`
+	Scale  *ScaleSubresource `json:"scale,omitempty"`
+
+	// Other subresources (e.g., pods/log or serviceaccounts/token)
+	Additional []Subresource `json:"additional,omitempty"`
+}
+
+// Read subresources, including those of bundles written when the scale
+// subresource was a boolean.
+func (s *Subresources) UnmarshalJSON(data []byte) error {
+	type subresources Subresources
+	var raw struct {
+		subresources
+		Scale json.RawMessage `json:"scale,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*s = Subresources(raw.subresources)
+
+	switch string(raw.Scale) {
+	case "", "null", "false":
+	case "true":
+		s.Scale = &ScaleSubresource{}
+	default:
+		s.Scale = &ScaleSubresource{}
+		if err := json.Unmarshal(raw.Scale, s.Scale); err != nil {
+			return fmt.Errorf("scale: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type ScaleSubresource struct {
+	SpecReplicasPath   string `json:"specReplicasPath,omitempty"`
+	StatusReplicasPath string `json:"statusReplicasPath,omitempty"`
+	LabelSelectorPath  string `json:"labelSelectorPath,omitempty"`
+}
+
+type Subresource struct {
+	Name   string           `json:"name"`
+	Verb   string           `json:"verb"`
+	Input  *ReferenceTarget `json:"input,omitempty"`
+	Output *ReferenceTarget `json:"output,omitempty"`
 }
 
 const (
@@ -0,0 +1,57 @@
+package spec
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSubresourcesUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want Subresources
+	}{
+		{"empty", `{}`, Subresources{}},
+		{"legacy scale", `{"status": true, "scale": true}`, Subresources{Status: true, Scale: &ScaleSubresource{}}},
+		{"legacy no scale", `{"scale": false}`, Subresources{}},
+		{
+			"scale paths",
+			`{"scale": {"specReplicasPath": ".spec.replicas"}, "additional": [{"name": "log", "verb": "get"}]}`,
+			Subresources{
+				Scale:      &ScaleSubresource{SpecReplicasPath: ".spec.replicas"},
+				Additional: []Subresource{{Name: "log", Verb: "get"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Subresources
+			if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubresourcesRoundTrip(t *testing.T) {
+	want := Subresources{Status: true, Scale: &ScaleSubresource{StatusReplicasPath: ".status.replicas"}}
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Subresources
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -1,6 +1,9 @@
 package spec
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/byrnedo/pjson"
 )
 
@@ -10,9 +13,19 @@ type Definition struct {
 }
 
 type DefinitionMeta struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	Deprecated  bool   `json:"deprecated,omitempty"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Deprecated  bool             `json:"deprecated,omitempty"`
+	Validations []ValidationRule `json:"validations,omitempty"`
+}
+
+// A CEL validation rule (x-kubernetes-validations).
+type ValidationRule struct {
+	Rule              string `json:"rule"`
+	Message           string `json:"message,omitempty"`
+	MessageExpression string `json:"messageExpression,omitempty"`
+	Reason            string `json:"reason,omitempty"`
+	FieldPath         string `json:"fieldPath,omitempty"`
 }
 
 type Property struct {
@@ -22,7 +35,8 @@ type Property struct {
 
 type PropertyMeta struct {
 	DefinitionMeta
-	Required bool `json:"required,omitempty"`
+	Required bool            `json:"required,omitempty"`
+	Default  json.RawMessage `json:"default,omitempty"`
 }
 
 var marshaler = pjson.New([]TypeVariant{
@@ -35,6 +49,7 @@ var marshaler = pjson.New([]TypeVariant{
 	&ArrayType{},
 	&MapType{},
 	&UnionType{},
+	&OptionalType{},
 	&ReferenceType{},
 	&UnknownType{},
 })
@@ -59,6 +74,10 @@ type TypeVariant interface {
 type StringType struct {
 	Enum   []string `json:"enum,omitempty"`
 	Format string   `json:"format,omitempty"`
+
+	MinLength *int64 `json:"minLength,omitempty"`
+	MaxLength *int64 `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
 }
 
 func (t StringType) Variant() string {
@@ -67,6 +86,12 @@ func (t StringType) Variant() string {
 
 type IntegerType struct {
 	Size int `json:"size,omitempty"`
+
+	Minimum          *int64 `json:"minimum,omitempty"`
+	Maximum          *int64 `json:"maximum,omitempty"`
+	ExclusiveMinimum bool   `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool   `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *int64 `json:"multipleOf,omitempty"`
 }
 
 func (t IntegerType) Variant() string {
@@ -75,6 +100,12 @@ func (t IntegerType) Variant() string {
 
 type FloatType struct {
 	Size int `json:"size,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum bool     `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool     `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
 }
 
 func (t FloatType) Variant() string {
@@ -98,6 +129,15 @@ func (t ObjectType) Variant() string {
 
 type ArrayType struct {
 	Values Type `json:"values"`
+
+	MinItems    *int64 `json:"minItems,omitempty"`
+	MaxItems    *int64 `json:"maxItems,omitempty"`
+	UniqueItems bool   `json:"uniqueItems,omitempty"`
+
+	ListType      string   `json:"listType,omitempty"`
+	ListMapKeys   []string `json:"listMapKeys,omitempty"`
+	PatchStrategy string   `json:"patchStrategy,omitempty"`
+	PatchMergeKey string   `json:"patchMergeKey,omitempty"`
 }
 
 func (t ArrayType) Variant() string {
@@ -106,12 +146,26 @@ func (t ArrayType) Variant() string {
 
 type MapType struct {
 	Values Type `json:"values"`
+
+	MinProperties *int64 `json:"minProperties,omitempty"`
+	MaxProperties *int64 `json:"maxProperties,omitempty"`
+
+	MapType string `json:"mapType,omitempty"`
 }
 
 func (t MapType) Variant() string {
 	return "map"
 }
 
+const (
+	ListTypeAtomic = "atomic"
+	ListTypeSet    = "set"
+	ListTypeMap    = "map"
+
+	MapTypeGranular = "granular"
+	MapTypeAtomic   = "atomic"
+)
+
 type UnionType struct {
 	Values []Type `json:"values"`
 }
@@ -136,6 +190,10 @@ func (t UnknownType) Variant() string {
 
 type ReferenceType struct {
 	Target ReferenceTarget `json:"target"`
+	// Constraints on the referenced type where it is used (e.g., by a field),
+	// as a type of the same variant as its definition. Only the constraints
+	// of this type are meaningful.
+	Constraints *Type `json:"constraints,omitempty"`
 }
 
 func (t ReferenceType) Variant() string {
@@ -147,6 +205,19 @@ type ReferenceTarget struct {
 	Name  string          `json:"name"`
 }
 
+func (t ReferenceTarget) String() string {
+	if t.Scope == nil {
+		return t.Name
+	}
+
+	name := fmt.Sprintf("%s/%s.%s", t.Scope.Group, t.Scope.Version, t.Name)
+	if t.Scope.Package != "" {
+		return t.Scope.Package + ":" + name
+	}
+
+	return name
+}
+
 type ReferenceScope struct {
 	Package string             `json:"package,omitempty"`
 	Group   APIGroupIdentifier `json:"group"`
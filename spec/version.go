@@ -0,0 +1,64 @@
+package spec
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var kubeVersion = regexp.MustCompile(`^v(\d+)(?:(alpha|beta)(\d+))?$`)
+
+// The stability of a Kubernetes API version, in increasing priority.
+var versionStability = map[string]int{"alpha": 0, "beta": 1, "": 2}
+
+// Compare API versions by Kubernetes version priority (as apimachinery's
+// version.CompareKubeAwareVersionStrings): GA versions before betas before
+// alphas, then by higher major and minor versions, then versions of other
+// forms in lexical order. Returns a positive number if a has priority over b.
+func compareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	am, aok := parseKubeVersion(a)
+	bm, bok := parseKubeVersion(b)
+	switch {
+	case !aok && !bok:
+		return strings.Compare(b, a)
+	case !aok:
+		return -1
+	case !bok:
+		return 1
+	}
+
+	for i := range am {
+		if am[i] != bm[i] {
+			return am[i] - bm[i]
+		}
+	}
+
+	return 0
+}
+
+// Parse a vMAJOR[(alpha|beta)MINOR] version into its stability, major and
+// minor version.
+func parseKubeVersion(v string) ([3]int, bool) {
+	m := kubeVersion.FindStringSubmatch(v)
+	if m == nil {
+		return [3]int{}, false
+	}
+
+	major, err := strconv.Atoi(m[1])
+	if err != nil {
+		return [3]int{}, false
+	}
+
+	minor := 0
+	if m[3] != "" {
+		if minor, err = strconv.Atoi(m[3]); err != nil {
+			return [3]int{}, false
+		}
+	}
+
+	return [3]int{versionStability[m[2]], major, minor}, true
+}
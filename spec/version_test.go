@@ -0,0 +1,26 @@
+package spec
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	// in decreasing priority, as apimachinery orders them
+	want := []string{"v10", "v2", "v1", "v11beta2", "v10beta3", "v3beta1", "v12alpha1", "v11alpha2", "foo1", "foo10"}
+
+	got := slices.Clone(want)
+	slices.Reverse(got)
+	sort.SliceStable(got, func(i, j int) bool {
+		return compareVersions(got[i], got[j]) > 0
+	})
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if compareVersions("v1", "v1") != 0 {
+		t.Error("a version has priority over itself")
+	}
+}
@@ -2,6 +2,7 @@ package walk
 
 import (
 	"os"
+	"path"
 	"strings"
 
 	"golang.org/x/mod/modfile"
@@ -10,12 +11,18 @@ import (
 	"github.com/kure-sh/ingest-go/spec"
 )
 
+// The name of the legacy Kubernetes API group, declared as +groupName=
+const coreGroup = "core"
+
 func APIPackages(conf *config.Config, local *LocalGoModule, pkgs []*Package) []*Package {
 	var apis []*Package
 
 	for _, pkg := range pkgs {
-		local.ResolvePackage(pkg)
-		pkg.Group = groupForPackage(conf, pkg)
+		root := local.Path
+		if dep := local.ResolvePackage(pkg); dep != nil {
+			root = dep.Mod.Path
+		}
+		pkg.Group = groupForPackage(conf, root, pkg)
 
 		if pkg.Imports().APIMachinery() {
 			apis = append(apis, pkg)
@@ -68,8 +75,20 @@ func (m LocalGoModule) ResolvePackage(pkg *Package) *modfile.Require {
 	return nil
 }
 
-func groupForPackage(conf *config.Config, pkg *Package) *PackageGroup {
-	resolved := conf.ResolvePackage(pkg.Path())
+// Find the group of a scanned package: as declared in kure.toml, or else by
+// its package markers, or else as a builtin extern. Markers come before
+// builtin externs so that the Kubernetes API's themselves can be scanned. The
+// root is the path of the Go module providing the package.
+func groupForPackage(conf *config.Config, root string, pkg *Package) *PackageGroup {
+	resolved := conf.DeclaredPackage(pkg.Path())
+	if resolved == nil {
+		if export := exportFromMarkers(root, pkg); export != nil {
+			resolved = conf.AddExport(*export)
+		}
+	}
+	if resolved == nil {
+		resolved = conf.ResolvePackage(pkg.Path())
+	}
 	if resolved == nil {
 		return nil
 	}
@@ -89,6 +108,43 @@ func groupForPackage(conf *config.Config, pkg *Package) *PackageGroup {
 	}
 }
 
+// Derive an export from the +groupName and +versionName package markers. The
+// version defaults to the last element of the package path, and the module is
+// named after the package's directory within the Go module root (without the
+// version directory), like the k8s.io/api/<module>/<version> packages.
+func exportFromMarkers(root string, pkg *Package) *config.Export {
+	if !pkg.comment.HasMarker("groupName") {
+		return nil
+	}
+
+	group := pkg.comment.Marker("groupName")
+	if group == "" {
+		group = coreGroup
+	}
+
+	version := pkg.comment.Marker("versionName")
+	if version == "" {
+		version = path.Base(pkg.Path())
+	}
+
+	dir := strings.TrimPrefix(strings.TrimPrefix(pkg.Path(), root), "/")
+	if path.Base(dir) == version {
+		dir = path.Dir(dir)
+	}
+
+	var module string
+	if dir != "" && dir != "." {
+		module = path.Base(dir)
+	}
+
+	return &config.Export{
+		Path:    pkg.Path(),
+		Module:  module,
+		Group:   group,
+		Version: version,
+	}
+}
+
 func withinModule(mod string, pkg string) bool {
 	return mod == pkg || strings.HasPrefix(pkg, mod+"/")
 }
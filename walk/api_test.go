@@ -0,0 +1,120 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/config"
+)
+
+// Write the files of a Go module into a temporary directory, and change into
+// it for the rest of the test.
+func testModule(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestGroupFromMarkersOverBuiltinExtern(t *testing.T) {
+	testModule(t, map[string]string{
+		"go.mod": "module k8s.io/api\n\ngo 1.19\n",
+		"apps/v1/doc.go": `// +k8s:openapi-gen=true
+// +groupName=apps
+
+// Package v1 is the v1 version of the apps API.
+package v1
+`,
+		"apps/v1/types.go": "package v1\n\ntype Deployment struct{}\n",
+	})
+
+	pkgs, err := LoadPackages("./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("loaded %d packages", len(pkgs))
+	}
+
+	conf := &config.Config{Name: "kubernetes"}
+	APIPackages(conf, &LocalGoModule{Path: "k8s.io/api"}, pkgs)
+
+	group := pkgs[0].Group
+	if group == nil || group.Name != "apps" || group.Version != "v1" {
+		t.Fatalf("group %+v, want apps/v1", group)
+	}
+
+	export := conf.Export("k8s.io/api/apps/v1")
+	if export == nil {
+		t.Fatal("no export added from the package markers")
+	}
+	if export.Group != "apps" || export.Module != "apps" {
+		t.Errorf("export group %q in module %q, want apps", export.Group, export.Module)
+	}
+}
+
+func TestModulesFromMarkers(t *testing.T) {
+	testModule(t, map[string]string{
+		"go.mod":              "module example.com/ops\n\ngo 1.19\n",
+		"api/cache/v1/doc.go": "// +groupName=cache.example.com\npackage v1\n",
+		"api/queue/v1/doc.go": "// +groupName=queue.example.com\npackage v1\n",
+	})
+
+	pkgs, err := LoadPackages("./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config.Config{Name: "ops"}
+	APIPackages(conf, &LocalGoModule{Path: "example.com/ops"}, pkgs)
+
+	for _, module := range []string{"cache", "queue"} {
+		export := conf.Export("example.com/ops/api/" + module + "/v1")
+		if export == nil {
+			t.Fatalf("no export added for %s", module)
+		}
+		if export.Module != module || export.Group != module+".example.com" {
+			t.Errorf("export %s in module %q, want %s.example.com in %s", export.Group, export.Module, module, module)
+		}
+	}
+}
+
+func TestGroupOfBuiltinExtern(t *testing.T) {
+	testModule(t, map[string]string{
+		"go.mod":         "module k8s.io/api\n\ngo 1.19\n",
+		"apps/v1/doc.go": "// Package v1 has no markers.\npackage v1\n",
+	})
+
+	pkgs, err := LoadPackages("./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config.Config{Name: "kubernetes"}
+	APIPackages(conf, &LocalGoModule{Path: "k8s.io/api"}, pkgs)
+
+	if group := pkgs[0].Group; group == nil || group.Name != "apps" {
+		t.Errorf("group %+v, want the builtin extern's", group)
+	}
+	if len(conf.Exports) != 0 {
+		t.Errorf("exports %+v added for a builtin extern", conf.Exports)
+	}
+}
@@ -1,7 +1,10 @@
 package walk
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 
 	"github.com/kure-sh/ingest-go/config"
 	"github.com/kure-sh/ingest-go/spec"
@@ -15,6 +18,9 @@ func GenerateBundle(gctx *GeneratorContext) (*spec.Bundle, error) {
 		if pkg == nil {
 			return nil, fmt.Errorf("exported package %s was not scanned", export.Path)
 		}
+		if pkg.comment.HasMarker("kubebuilder:skipversion") {
+			continue
+		}
 
 		gv, err := NewGenerator(gctx, pkg).Generate()
 		if err != nil {
@@ -24,6 +30,16 @@ func GenerateBundle(gctx *GeneratorContext) (*spec.Bundle, error) {
 		gvs = append(gvs, gv)
 	}
 
+	return assembleBundle(gctx, gvs)
+}
+
+// Override, prune, merge and check the generated group-versions, and bundle
+// them.
+func assembleBundle(gctx *GeneratorContext, gvs []*spec.APIGroupVersion) (*spec.Bundle, error) {
+	if err := applyOverrides(gctx.Config, gvs); err != nil {
+		return nil, err
+	}
+
 	prune := false
 	for _, export := range gctx.Config.Exports {
 		if export.Prune {
@@ -40,6 +56,10 @@ func GenerateBundle(gctx *GeneratorContext) (*spec.Bundle, error) {
 		return nil, err
 	}
 
+	if err := checkReferences(gctx.Bundles, mgvs); err != nil {
+		return nil, err
+	}
+
 	bundle, err := spec.NewBundle(mgvs)
 	if err != nil {
 		return nil, err
@@ -156,11 +176,14 @@ func pruneDefinitions(conf *config.Config, gvs []*spec.APIGroupVersion) {
 	}
 }
 
+// A group-version merged into another (or itself, when renaming definitions),
+// and the names its definitions took.
+type merge struct {
+	from, to *spec.APIGroupVersion
+	names    map[string]string
+}
+
 func applyMerges(conf *config.Config, gvs []*spec.APIGroupVersion) ([]*spec.APIGroupVersion, error) {
-	type merge struct {
-		from spec.APIGroupIdentifier
-		to   spec.APIGroupIdentifier
-	}
 	merged := make([]*spec.APIGroupVersion, 0, len(gvs))
 	var merges []merge
 
@@ -179,115 +202,187 @@ func applyMerges(conf *config.Config, gvs []*spec.APIGroupVersion) ([]*spec.APIG
 			export.Merge.Version = export.Version
 		}
 
-		target := mergeTarget(gvs, export.Merge)
-		if target == nil {
-			return nil, fmt.Errorf("merge target not found for %s/%s", gv.Group.Name, gv.Version)
+		target, err := mergeTarget(conf, gvs, gv, export.Merge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge %s/%s: %w", gv.Group.Name, gv.Version, err)
 		}
 
-		if err := applyMerge(export.Merge, gv, target); err != nil {
+		names, err := applyMerge(export.Merge, gv, target)
+		if err != nil {
 			return nil, fmt.Errorf("failed to merge %s/%s: %w", gv.Group.Name, gv.Version, err)
 		}
 
-		merges = append(merges, merge{from: gv.Group, to: target.Group})
+		merges = append(merges, merge{from: gv, to: target, names: names})
 	}
 
+	var errs []error
 	for _, m := range merges {
-		updateReferences(merged, m.from, m.to)
+		errs = append(errs, updateReferences(merged, m))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
 	return merged, nil
 }
 
-func applyMerge(spec *config.Merge, from, to *spec.APIGroupVersion) error {
-	// TODO: dependencies
+// Add the definitions and dependencies of one group-version to another,
+// returning the names of the merged definitions in the target.
+func applyMerge(m *config.Merge, from, to *spec.APIGroupVersion) (map[string]string, error) {
+	switch m.Conflict {
+	case "", config.MergeConflictError, config.MergeConflictKeep, config.MergeConflictReplace:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q", m.Conflict)
+	}
 
-	include := make(map[string]struct{}, len(spec.Include))
-	if spec.Include != nil {
-		for _, inc := range spec.Include {
-			include[inc] = struct{}{}
+	var errs []error
+	for name := range m.Rename {
+		if !slices.ContainsFunc(from.Definitions, func(def spec.Definition) bool { return def.Name == name }) {
+			errs = append(errs, fmt.Errorf("cannot rename %s: not defined", name))
 		}
 	}
 
+	names := make(map[string]string, len(from.Definitions))
+	defs := make([]spec.Definition, 0, len(from.Definitions))
 	for _, def := range from.Definitions {
-		if _, included := include[def.Name]; len(include) > 0 && !included {
+		if len(m.Include) > 0 && !slices.Contains(m.Include, def.Name) {
 			continue
 		}
 
-		to.Definitions = append(to.Definitions, def)
+		name := def.Name
+		if renamed, ok := m.Rename[name]; ok {
+			name = renamed
+		}
+
+		names[def.Name] = name
+		def.Name = name
+		defs = append(defs, def)
 	}
 
-	return nil
-}
+	// local references follow the definitions they refer to
+	for i := range defs {
+		visitReferences(&defs[i].Value, func(ref *spec.ReferenceType) {
+			if ref.Target.Scope != nil {
+				return
+			}
 
-func updateReferences(gvs []*spec.APIGroupVersion, from, to spec.APIGroupIdentifier) {
-	for _, gv := range gvs {
-		for i := range gv.Definitions {
-			t := &gv.Definitions[i].Value
-			updateReference(t, gv.Group, from, to)
-		}
+			if name, ok := names[ref.Target.Name]; ok {
+				ref.Target.Name = name
+			} else if slices.ContainsFunc(from.Definitions, func(def spec.Definition) bool { return def.Name == ref.Target.Name }) {
+				errs = append(errs, fmt.Errorf("%s refers to %s, which is not merged", defs[i].Name, ref.Target.Name))
+			}
+		})
 	}
-}
 
-func updateReference(t *spec.Type, loc, from, to spec.APIGroupIdentifier) {
-	switch v := t.Variant.(type) {
-	case *spec.ReferenceType:
-		if v.Target.Scope == nil {
-			return
+	for _, def := range defs {
+		i := slices.IndexFunc(to.Definitions, func(existing spec.Definition) bool { return existing.Name == def.Name })
+		if i < 0 {
+			to.Definitions = append(to.Definitions, def)
+			continue
 		}
 
-		if v.Target.Scope.Group.Same(from) {
-			if loc.Same(to) {
-				v.Target.Scope = nil
-			} else {
-				v.Target.Scope.Group = to
-			}
+		switch m.Conflict {
+		case config.MergeConflictKeep:
+		case config.MergeConflictReplace:
+			to.Definitions[i] = def
+		default:
+			errs = append(errs, fmt.Errorf("duplicate definition %s in %s/%s", def.Name, to.Group.Name, to.Version))
 		}
+	}
 
-	case *spec.ArrayType:
-		updateReference(&v.Values, loc, from, to)
-	case *spec.MapType:
-		updateReference(&v.Values, loc, from, to)
-	case *spec.OptionalType:
-		updateReference(&v.Value, loc, from, to)
-	case *spec.ObjectType:
-		for i := range v.Inherit {
-			updateReference(&v.Inherit[i], loc, from, to)
-		}
-		for i := range v.Properties {
-			updateReference(&v.Properties[i].Value, loc, from, to)
+	for _, dep := range from.Dependencies {
+		i := slices.IndexFunc(to.Dependencies, func(existing spec.APIDependency) bool { return existing.Package == dep.Package })
+		if i < 0 {
+			to.Dependencies = append(to.Dependencies, dep)
+		} else if to.Dependencies[i].Version != dep.Version {
+			errs = append(errs, fmt.Errorf("version mismatch of dependency %s", dep.Package))
 		}
-	case *spec.ResourceType:
-		for i := range v.Properties {
-			updateReference(&v.Properties[i].Value, loc, from, to)
-		}
-	case *spec.UnionType:
-		for i := range v.Values {
-			updateReference(&v.Values[i], loc, from, to)
+	}
+	sort.Slice(to.Dependencies, func(i, j int) bool {
+		return to.Dependencies[i].Package < to.Dependencies[j].Package
+	})
+
+	return names, errors.Join(errs...)
+}
+
+// Point the references to a merged or renamed group-version at its target,
+// by the new names of its definitions.
+func updateReferences(gvs []*spec.APIGroupVersion, m merge) error {
+	var errs []error
+
+	for _, gv := range gvs {
+		for i := range gv.Definitions {
+			def := &gv.Definitions[i]
+
+			visitReferences(&def.Value, func(ref *spec.ReferenceType) {
+				scope := ref.Target.Scope
+				if scope == nil {
+					if name, ok := m.names[ref.Target.Name]; ok && gv == m.from {
+						ref.Target.Name = name
+					}
+					return
+				}
+				if scope.Package != "" || !scope.Group.Same(m.from.Group) || scope.Version != m.from.Version {
+					return
+				}
+
+				name, ok := m.names[ref.Target.Name]
+				if !ok {
+					errs = append(errs, fmt.Errorf("%s/%s %s refers to %s, which is not merged",
+						gv.Group.Name, gv.Version, def.Name, ref.Target.Name))
+					return
+				}
+
+				ref.Target.Name = name
+				if gv == m.to {
+					ref.Target.Scope = nil
+				} else {
+					ref.Target.Scope = &spec.ReferenceScope{Group: m.to.Group, Version: m.to.Version}
+				}
+			})
 		}
 	}
+
+	return errors.Join(errs...)
 }
 
 func exportFor(conf *config.Config, gv *spec.APIGroupVersion) *config.Export {
-	for i, export := range conf.Exports {
+	for _, export := range conf.Exports {
 		if export.Is(gv) {
-			return &conf.Exports[i]
+			return export
 		}
 	}
 
 	return nil
 }
 
-func mergeTarget(gvs []*spec.APIGroupVersion, merge *config.Merge) *spec.APIGroupVersion {
+// Find the group-version a merge adds to, which must not be merged itself.
+func mergeTarget(conf *config.Config, gvs []*spec.APIGroupVersion, from *spec.APIGroupVersion, merge *config.Merge) (*spec.APIGroupVersion, error) {
+	var target *spec.APIGroupVersion
+
 	for _, gv := range gvs {
 		var module string
 		if gv.Group.Module != nil {
 			module = *gv.Group.Module
 		}
 
-		if merge.Module == module && merge.Version == gv.Version {
-			return gv
+		if gv == from || merge.Module != module || merge.Version != gv.Version ||
+			(merge.Group != "" && merge.Group != gv.Group.Name) {
+			continue
 		}
+		if target != nil {
+			return nil, fmt.Errorf("ambiguous merge target in module %q: %s or %s (set group)", merge.Module, target.Group.Name, gv.Group.Name)
+		}
+
+		target = gv
 	}
 
-	return nil
+	if target == nil {
+		return nil, fmt.Errorf("merge target not found")
+	}
+	if export := exportFor(conf, target); export != nil && export.Merge != nil {
+		return nil, fmt.Errorf("merge target %s/%s is merged itself", target.Group.Name, target.Version)
+	}
+
+	return target, nil
 }
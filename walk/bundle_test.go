@@ -0,0 +1,230 @@
+package walk
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// An object definition with a property referring to each target.
+func referringDefinition(name string, targets ...spec.ReferenceTarget) spec.Definition {
+	props := make([]spec.Property, len(targets))
+	for i, target := range targets {
+		props[i] = spec.Property{
+			PropertyMeta: spec.PropertyMeta{DefinitionMeta: spec.DefinitionMeta{Name: strings.ToLower(target.Name)}},
+			Value:        spec.Type{Variant: &spec.ReferenceType{Target: target}},
+		}
+	}
+
+	return spec.Definition{
+		DefinitionMeta: spec.DefinitionMeta{Name: name},
+		Value:          spec.Type{Variant: &spec.ObjectType{Properties: props}},
+	}
+}
+
+func testGroupVersion(group string, defs ...spec.Definition) *spec.APIGroupVersion {
+	return &spec.APIGroupVersion{
+		Group:       spec.APIGroupIdentifier{Name: group},
+		Version:     "v1",
+		Definitions: defs,
+	}
+}
+
+// The targets of the references of a group-version, by definition and
+// property.
+func referenceTargets(gv *spec.APIGroupVersion) map[string]string {
+	targets := make(map[string]string)
+
+	for _, def := range gv.Definitions {
+		obj, ok := def.Value.Variant.(*spec.ObjectType)
+		if !ok {
+			continue
+		}
+		for _, prop := range obj.Properties {
+			if ref, ok := prop.Value.Variant.(*spec.ReferenceType); ok {
+				targets[def.Name+"."+prop.Name] = ref.Target.String()
+			}
+		}
+	}
+
+	return targets
+}
+
+func TestApplyMerges(t *testing.T) {
+	internalScope := &spec.ReferenceScope{Group: spec.APIGroupIdentifier{Name: "internal.example.com"}, Version: "v1"}
+
+	widgets := testGroupVersion("widgets.example.com",
+		referringDefinition("Widget", spec.ReferenceTarget{Scope: internalScope, Name: "Gadget"}))
+	widgets.Dependencies = []spec.APIDependency{{Package: "kubernetes", Version: "1.29"}}
+
+	internal := testGroupVersion("internal.example.com",
+		referringDefinition("Gadget", spec.ReferenceTarget{Name: "Helper"}),
+		referringDefinition("Helper"))
+	internal.Dependencies = []spec.APIDependency{{Package: "istio", Version: "1.20"}, {Package: "kubernetes", Version: "1.29"}}
+
+	conf := &config.Config{Exports: []*config.Export{
+		{Group: "widgets.example.com", Version: "v1"},
+		{Group: "internal.example.com", Version: "v1", Merge: &config.Merge{
+			Group:  "widgets.example.com",
+			Rename: map[string]string{"Gadget": "WidgetGadget"},
+		}},
+	}}
+
+	merged, err := applyMerges(conf, []*spec.APIGroupVersion{widgets, internal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 || merged[0] != widgets {
+		t.Fatalf("merged into %d group-versions", len(merged))
+	}
+
+	if got, want := definitionNames(widgets), []string{"Widget", "WidgetGadget", "Helper"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("definitions %v, want %v", got, want)
+	}
+
+	wantTargets := map[string]string{
+		"Widget.gadget":       "WidgetGadget",
+		"WidgetGadget.helper": "Helper",
+	}
+	if got := referenceTargets(widgets); !reflect.DeepEqual(got, wantTargets) {
+		t.Errorf("references %v, want %v", got, wantTargets)
+	}
+
+	wantDeps := []spec.APIDependency{{Package: "istio", Version: "1.20"}, {Package: "kubernetes", Version: "1.29"}}
+	if !reflect.DeepEqual(widgets.Dependencies, wantDeps) {
+		t.Errorf("dependencies %v, want %v", widgets.Dependencies, wantDeps)
+	}
+}
+
+func TestApplyMergeConflict(t *testing.T) {
+	tests := []struct {
+		conflict string
+		want     string // the target of the merged Widget's reference, or "" for an error
+	}{
+		{"", ""},
+		{config.MergeConflictError, ""},
+		{config.MergeConflictKeep, "Original"},
+		{config.MergeConflictReplace, "Merged"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.conflict, func(t *testing.T) {
+			to := testGroupVersion("widgets.example.com", referringDefinition("Widget", spec.ReferenceTarget{Name: "Original"}))
+			from := testGroupVersion("internal.example.com", referringDefinition("Widget", spec.ReferenceTarget{Name: "Merged"}))
+
+			_, err := applyMerge(&config.Merge{Conflict: tt.conflict}, from, to)
+			if tt.want == "" {
+				if err == nil {
+					t.Error("expected a duplicate definition error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := referenceTargets(to)["Widget."+strings.ToLower(tt.want)]; got != tt.want {
+				t.Errorf("references %v, want one to %s", referenceTargets(to), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMergeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		merge config.Merge
+	}{
+		{"unknown conflict policy", config.Merge{Conflict: "overwrite"}},
+		{"rename of undefined", config.Merge{Rename: map[string]string{"Missing": "Found"}}},
+		{"reference to excluded", config.Merge{Include: []string{"Gadget"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := testGroupVersion("widgets.example.com")
+			from := testGroupVersion("internal.example.com",
+				referringDefinition("Gadget", spec.ReferenceTarget{Name: "Helper"}),
+				referringDefinition("Helper"))
+
+			if _, err := applyMerge(&tt.merge, from, to); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMergeTarget(t *testing.T) {
+	from := testGroupVersion("internal.example.com")
+	a := testGroupVersion("a.example.com")
+	b := testGroupVersion("b.example.com")
+	gvs := []*spec.APIGroupVersion{from, a, b}
+
+	if _, err := mergeTarget(&config.Config{}, gvs, from, &config.Merge{Version: "v1"}); err == nil {
+		t.Error("expected an ambiguous target error")
+	}
+
+	target, err := mergeTarget(&config.Config{}, gvs, from, &config.Merge{Group: "b.example.com", Version: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != b {
+		t.Errorf("target %s, want b.example.com", target.Group.Name)
+	}
+
+	conf := &config.Config{Exports: []*config.Export{
+		{Group: "b.example.com", Version: "v1", Merge: &config.Merge{Group: "a.example.com"}},
+	}}
+	if _, err := mergeTarget(conf, gvs, from, &config.Merge{Group: "b.example.com", Version: "v1"}); err == nil {
+		t.Error("expected an error for a target which is merged itself")
+	}
+}
+
+func TestBundleFilesSharedDirectory(t *testing.T) {
+	bundle, err := spec.NewBundle([]*spec.APIGroupVersion{
+		testGroupVersion("cache.example.com"),
+		testGroupVersion("queue.example.com"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = BundleFiles(bundle, "out")
+	if err == nil || !strings.Contains(err.Error(), "would both be written to out") {
+		t.Errorf("error %v, want one for groups sharing out/group.json", err)
+	}
+}
+
+func TestGenerateBundleSkipVersion(t *testing.T) {
+	testModule(t, map[string]string{
+		"go.mod":          "module example.com/ops\n\ngo 1.19\n",
+		"api/v1/doc.go":   "// +groupName=example.com\npackage v1\n",
+		"api/v1/types.go": "package v1\n\ntype Widget struct {\n\tName string `json:\"name\"`\n}\n",
+		"api/v2/doc.go":   "// +groupName=example.com\n// +kubebuilder:skipversion\npackage v2\n",
+		"api/v2/types.go": "package v2\n\ntype Widget struct {\n\tName string `json:\"name\"`\n}\n",
+	})
+
+	pkgs, err := LoadPackages("./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config.Config{Name: "ops"}
+	APIPackages(conf, &LocalGoModule{Path: "example.com/ops"}, pkgs)
+
+	bundle, err := GenerateBundle(NewGeneratorContext(conf, pkgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var versions []string
+	for _, gv := range bundle.Versions {
+		versions = append(versions, gv.Version)
+	}
+	if !reflect.DeepEqual(versions, []string{"v1"}) {
+		t.Errorf("versions %v, want only v1", versions)
+	}
+}
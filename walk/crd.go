@@ -0,0 +1,218 @@
+package walk
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+type CustomResourceDefinition struct {
+	APIVersion string                       `json:"apiVersion"`
+	Kind       string                       `json:"kind"`
+	Metadata   CustomResourceDefinitionMeta `json:"metadata"`
+	Spec       CustomResourceDefinitionSpec `json:"spec"`
+}
+
+type CustomResourceDefinitionMeta struct {
+	Name string `json:"name"`
+}
+
+type CustomResourceDefinitionSpec struct {
+	Group    string                            `json:"group"`
+	Names    CustomResourceDefinitionNames     `json:"names"`
+	Scope    string                            `json:"scope"`
+	Versions []CustomResourceDefinitionVersion `json:"versions"`
+}
+
+type CustomResourceDefinitionNames struct {
+	Kind       string   `json:"kind"`
+	ListKind   string   `json:"listKind"`
+	Plural     string   `json:"plural"`
+	Singular   string   `json:"singular"`
+	ShortNames []string `json:"shortNames,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+type CustomResourceDefinitionVersion struct {
+	Name                     string                      `json:"name"`
+	Served                   bool                        `json:"served"`
+	Storage                  bool                        `json:"storage"`
+	Deprecated               bool                        `json:"deprecated,omitempty"`
+	DeprecationWarning       *string                     `json:"deprecationWarning,omitempty"`
+	Schema                   CustomResourceValidation    `json:"schema"`
+	Subresources             *CustomResourceSubresources `json:"subresources,omitempty"`
+	AdditionalPrinterColumns []spec.PrinterColumn        `json:"additionalPrinterColumns,omitempty"`
+}
+
+type CustomResourceValidation struct {
+	OpenAPIV3Schema *Schema `json:"openAPIV3Schema"`
+}
+
+type CustomResourceSubresources struct {
+	Status *struct{}              `json:"status,omitempty"`
+	Scale  *spec.ScaleSubresource `json:"scale,omitempty"`
+}
+
+// Build a CustomResourceDefinition for every resource kind in the bundle,
+// with a version for each group-version which defines it. References to
+// dependencies are inlined from their bundles.
+func CustomResourceDefinitions(bundle *spec.Bundle, deps map[string]*spec.Bundle) ([]*CustomResourceDefinition, error) {
+	type crdKey struct {
+		group, kind string
+	}
+
+	converter := newSchemaConverter(newDefinitionIndex(bundle, deps), dialectStructural)
+
+	var crds []*CustomResourceDefinition
+	index := make(map[crdKey]*CustomResourceDefinition)
+
+	for _, gv := range bundle.Versions {
+		for _, def := range gv.Definitions {
+			res, ok := def.Value.Variant.(*spec.ResourceType)
+			if !ok {
+				continue
+			}
+			meta := res.Metadata
+
+			k := crdKey{gv.Group.Name, meta.Kind}
+			crd := index[k]
+			if crd == nil {
+				crd = newCustomResourceDefinition(gv.Group.Name, meta)
+				index[k] = crd
+				crds = append(crds, crd)
+			} else if crd.Spec.Names.Plural != meta.Name {
+				return nil, fmt.Errorf("%s/%s %s: resource name %s differs from %s",
+					gv.Group.Name, gv.Version, def.Name, meta.Name, crd.Spec.Names.Plural)
+			}
+
+			schema, err := converter.definition(&indexedDefinition{typeContext{gv: gv}, &def})
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s %s: %w", gv.Group.Name, gv.Version, def.Name, err)
+			}
+
+			version := CustomResourceDefinitionVersion{
+				Name:                     gv.Version,
+				Served:                   !meta.Unserved,
+				Storage:                  meta.Storage,
+				Deprecated:               meta.DeprecatedVersion,
+				Schema:                   CustomResourceValidation{OpenAPIV3Schema: schema},
+				AdditionalPrinterColumns: meta.PrinterColumns,
+			}
+			if meta.DeprecationWarning != "" {
+				version.DeprecationWarning = &meta.DeprecationWarning
+			}
+			if meta.Subresources.Status || meta.Subresources.Scale != nil {
+				version.Subresources = &CustomResourceSubresources{Scale: meta.Subresources.Scale}
+				if meta.Subresources.Status {
+					version.Subresources.Status = &struct{}{}
+				}
+			}
+
+			crd.Spec.Versions = append(crd.Spec.Versions, version)
+		}
+	}
+
+	for _, crd := range crds {
+		if err := selectStorageVersion(crd); err != nil {
+			return nil, err
+		}
+	}
+
+	return crds, nil
+}
+
+func newCustomResourceDefinition(group string, meta spec.ResourceMeta) *CustomResourceDefinition {
+	scope := "Namespaced"
+	if meta.Scope == spec.ScopeCluster {
+		scope = "Cluster"
+	}
+
+	return &CustomResourceDefinition{
+		APIVersion: "apiextensions.k8s.io/v1",
+		Kind:       "CustomResourceDefinition",
+		Metadata:   CustomResourceDefinitionMeta{Name: meta.Name + "." + group},
+		Spec: CustomResourceDefinitionSpec{
+			Group: group,
+			Names: CustomResourceDefinitionNames{
+				Kind:       meta.Kind,
+				ListKind:   meta.Kind + "List",
+				Plural:     meta.Name,
+				Singular:   meta.SingularName,
+				ShortNames: meta.ShortNames,
+				Categories: meta.Categories,
+			},
+			Scope: scope,
+		},
+	}
+}
+
+// Exactly one version must be stored: a single version is, by default.
+func selectStorageVersion(crd *CustomResourceDefinition) error {
+	storage := 0
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			storage++
+		}
+	}
+
+	switch {
+	case storage == 0 && len(crd.Spec.Versions) == 1:
+		crd.Spec.Versions[0].Storage = true
+	case storage == 0:
+		return fmt.Errorf("%s: no storage version among multiple versions (use +kubebuilder:storageversion)", crd.Metadata.Name)
+	case storage > 1:
+		return fmt.Errorf("%s: multiple storage versions", crd.Metadata.Name)
+	}
+
+	return nil
+}
+
+// Write a <group>_<plural>.yaml manifest for each CustomResourceDefinition.
+func WriteCRDs(bundle *spec.Bundle, deps map[string]*spec.Bundle, out string) error {
+	files, err := CRDFiles(bundle, deps, out)
+	if err != nil {
+		return err
+	}
+
+	return files.Write()
+}
+
+// The manifests of CustomResourceDefinitions, in the directory out.
+func CRDFiles(bundle *spec.Bundle, deps map[string]*spec.Bundle, out string) (FileSet, error) {
+	crds, err := CustomResourceDefinitions(bundle, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	var files FileSet
+	for _, crd := range crds {
+		data, err := marshalYAML(crd)
+		if err != nil {
+			return nil, err
+		}
+
+		filename := fmt.Sprintf("%s_%s.yaml", crd.Spec.Group, crd.Spec.Names.Plural)
+		files.addData(path.Join(out, filename), append([]byte("---\n"), data...))
+	}
+
+	return files, nil
+}
+
+// Marshal to YAML through JSON, keeping the order of fields.
+func marshalYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,194 @@
+package walk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"strconv"
+	"strings"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// Read the default value of a field from its +kubebuilder:default or +default
+// marker, as JSON matching the field's type.
+func (g *Generator) defaultValue(comment Comment, t spec.Type) (json.RawMessage, error) {
+	value := comment.Marker("kubebuilder:default")
+	if value == "" {
+		value = comment.Marker("default")
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	var parsed any
+	var err error
+
+	if name, ok := strings.CutPrefix(value, "ref("); ok && strings.HasSuffix(name, ")") {
+		parsed, err = g.constantDefault(strings.TrimSuffix(name, ")"))
+	} else {
+		parsed, err = parseDefault(value, t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid default %s: %w", value, err)
+	}
+
+	return json.Marshal(parsed)
+}
+
+// Resolve a ref(Name) default to the value of a constant in the package.
+func (g *Generator) constantDefault(name string) (any, error) {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	for _, c := range g.decls.Constants {
+		if c.Name() != name {
+			continue
+		}
+
+		val := c.Val()
+		switch val.Kind() {
+		case constant.String:
+			return constant.StringVal(val), nil
+		case constant.Bool:
+			return constant.BoolVal(val), nil
+		case constant.Int:
+			return json.Number(val.ExactString()), nil
+		case constant.Float:
+			f, _ := constant.Float64Val(val)
+			return f, nil
+		}
+
+		return nil, fmt.Errorf("unsupported constant %s", name)
+	}
+
+	return nil, fmt.Errorf("constant %s not found", name)
+}
+
+func parseDefault(value string, t spec.Type) (any, error) {
+	value = strings.TrimSpace(value)
+
+	switch v := t.Variant.(type) {
+	case *spec.OptionalType:
+		if value == "null" {
+			return nil, nil
+		}
+		return parseDefault(value, v.Value)
+
+	case *spec.StringType:
+		return unquoteMarker(value)
+
+	case *spec.IntegerType:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case *spec.FloatType:
+		return strconv.ParseFloat(value, 64)
+
+	case *spec.BooleanType:
+		return strconv.ParseBool(value)
+
+	case *spec.ArrayType:
+		if strings.HasPrefix(value, "[") {
+			return decodeJSON(value)
+		}
+
+		items := []any{}
+		for _, item := range splitDefaultList(value) {
+			parsed, err := parseDefault(item, v.Values)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, parsed)
+		}
+		return items, nil
+
+	case *spec.MapType, *spec.ObjectType, *spec.ResourceType:
+		if value == "{}" {
+			return map[string]any{}, nil
+		}
+
+		parsed, err := decodeJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := parsed.(map[string]any); !ok {
+			return nil, fmt.Errorf("expected an object")
+		}
+		return parsed, nil
+
+	case *spec.UnionType:
+		for _, vt := range v.Values {
+			if parsed, err := parseDefault(value, vt); err == nil {
+				return parsed, nil
+			}
+		}
+		return nil, fmt.Errorf("no matching union variant")
+
+	default:
+		// The target of a reference is not known here: take the value as JSON
+		// if possible, or as a bare string (e.g., an enum constant).
+		if parsed, err := decodeJSON(value); err == nil {
+			return parsed, nil
+		}
+		return unquoteMarker(value)
+	}
+}
+
+func decodeJSON(value string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+
+	var parsed any
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("trailing data after JSON value")
+	}
+
+	return parsed, nil
+}
+
+// Split a {a,b,c} or a;b;c marker list, respecting quoted items.
+func splitDefaultList(value string) (items []string) {
+	if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+		value = value[1 : len(value)-1]
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	var item bytes.Buffer
+	var quote byte
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' && i+1 < len(value) {
+				item.WriteByte(c)
+				i++
+				c = value[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == ',' || c == ';':
+			items = append(items, strings.TrimSpace(item.String()))
+			item.Reset()
+			continue
+		}
+
+		item.WriteByte(c)
+	}
+
+	return append(items, strings.TrimSpace(item.String()))
+}
@@ -0,0 +1,92 @@
+package walk
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+func TestParseDefault(t *testing.T) {
+	str := spec.Type{Variant: &spec.StringType{}}
+	integer := spec.Type{Variant: &spec.IntegerType{}}
+
+	tests := []struct {
+		name  string
+		value string
+		t     spec.Type
+		want  any
+	}{
+		{"bare string", "Always", str, "Always"},
+		{"quoted string", `"a, b"`, str, "a, b"},
+		{"integer", " 42 ", integer, int64(42)},
+		{"float", "1.5", spec.Type{Variant: &spec.FloatType{}}, 1.5},
+		{"boolean", "true", spec.Type{Variant: &spec.BooleanType{}}, true},
+		{"null", "null", spec.Type{Variant: &spec.OptionalType{Value: integer}}, nil},
+		{"optional", "3", spec.Type{Variant: &spec.OptionalType{Value: integer}}, int64(3)},
+		{"list", `{a,"b,c"}`, spec.Type{Variant: &spec.ArrayType{Values: str}}, []any{"a", "b,c"}},
+		{"semicolon list", "1;2", spec.Type{Variant: &spec.ArrayType{Values: integer}}, []any{int64(1), int64(2)}},
+		{"empty list", "{}", spec.Type{Variant: &spec.ArrayType{Values: str}}, []any{}},
+		{"JSON list", `["a"]`, spec.Type{Variant: &spec.ArrayType{Values: str}}, []any{"a"}},
+		{"empty object", "{}", spec.Type{Variant: &spec.ObjectType{}}, map[string]any{}},
+		{"JSON map", `{"a": 1}`, spec.Type{Variant: &spec.MapType{Values: integer}}, map[string]any{"a": json.Number("1")}},
+		{"union", "8080", spec.Type{Variant: &spec.UnionType{Values: []spec.Type{integer, str}}}, int64(8080)},
+		{"union string", "http", spec.Type{Variant: &spec.UnionType{Values: []spec.Type{integer, str}}}, "http"},
+		{"reference JSON", "5", spec.Type{Variant: &spec.ReferenceType{}}, json.Number("5")},
+		{"reference constant", "Delete", spec.Type{Variant: &spec.ReferenceType{}}, "Delete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDefault(tt.value, tt.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDefaultInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		t     spec.Type
+	}{
+		{"integer", "4.5", spec.Type{Variant: &spec.IntegerType{}}},
+		{"boolean", "yes", spec.Type{Variant: &spec.BooleanType{}}},
+		{"object", "[1]", spec.Type{Variant: &spec.ObjectType{}}},
+		{"trailing JSON", `{"a": 1} {}`, spec.Type{Variant: &spec.MapType{}}},
+		{"union", "x", spec.Type{Variant: &spec.UnionType{Values: []spec.Type{{Variant: &spec.IntegerType{}}}}}},
+	}
+
+	for _, tt := range tests {
+		if got, err := parseDefault(tt.value, tt.t); err == nil {
+			t.Errorf("%s: expected an error, got %#v", tt.name, got)
+		}
+	}
+}
+
+func TestSplitDefaultList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"{}", nil},
+		{"a,b", []string{"a", "b"}},
+		{"{a, b}", []string{"a", "b"}},
+		{`"a;b";c`, []string{`"a;b"`, "c"}},
+		{`"a\",b",c`, []string{`"a\",b"`, "c"}},
+		{"`a,b`,c", []string{"`a,b`", "c"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitDefaultList(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
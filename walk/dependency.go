@@ -0,0 +1,113 @@
+package walk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// Read the bundles of all dependencies which declare one, indexed by name.
+func LoadDependencyBundles(conf *config.Config) (map[string]*spec.Bundle, error) {
+	bundles := make(map[string]*spec.Bundle)
+
+	for _, dep := range conf.Dependencies {
+		if dep.Bundle == "" {
+			continue
+		}
+
+		bundle, err := spec.ReadBundle(dep.Bundle)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %q: %w", dep.Name, err)
+		}
+
+		bundles[dep.Name] = bundle
+	}
+
+	return bundles, nil
+}
+
+// Check that every reference into a dependency with a bundle names a
+// definition which exists in that bundle.
+func checkReferences(bundles map[string]*spec.Bundle, gvs []*spec.APIGroupVersion) error {
+	type target struct {
+		pkg     string
+		version string
+		name    string
+		module  string
+		group   string
+	}
+
+	known := make(map[target]struct{})
+	for name, bundle := range bundles {
+		for _, gv := range bundle.Versions {
+			var module string
+			if gv.Group.Module != nil {
+				module = *gv.Group.Module
+			}
+
+			for _, def := range gv.Definitions {
+				known[target{name, gv.Version, def.Name, module, gv.Group.Name}] = struct{}{}
+			}
+		}
+	}
+
+	var errs []error
+	for _, gv := range gvs {
+		for _, def := range gv.Definitions {
+			visitReferences(&def.Value, func(ref *spec.ReferenceType) {
+				scope := ref.Target.Scope
+				if scope == nil || bundles[scope.Package] == nil {
+					return
+				}
+
+				var module string
+				if scope.Group.Module != nil {
+					module = *scope.Group.Module
+				}
+
+				if _, ok := known[target{scope.Package, scope.Version, ref.Target.Name, module, scope.Group.Name}]; !ok {
+					errs = append(errs, fmt.Errorf("%s/%s %s: %s/%s %s not found in %s",
+						gv.Group.Name, gv.Version, def.Name,
+						scope.Group.Name, scope.Version, ref.Target.Name, scope.Package))
+				}
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid references: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Call fn for every reference within t.
+func visitReferences(t *spec.Type, fn func(*spec.ReferenceType)) {
+	switch v := t.Variant.(type) {
+	case *spec.ReferenceType:
+		fn(v)
+	case *spec.ArrayType:
+		visitReferences(&v.Values, fn)
+	case *spec.MapType:
+		visitReferences(&v.Values, fn)
+	case *spec.OptionalType:
+		visitReferences(&v.Value, fn)
+	case *spec.ObjectType:
+		for i := range v.Inherit {
+			visitReferences(&v.Inherit[i], fn)
+		}
+		for i := range v.Properties {
+			visitReferences(&v.Properties[i].Value, fn)
+		}
+	case *spec.ResourceType:
+		for i := range v.Properties {
+			visitReferences(&v.Properties[i].Value, fn)
+		}
+	case *spec.UnionType:
+		for i := range v.Values {
+			visitReferences(&v.Values[i], fn)
+		}
+	}
+}
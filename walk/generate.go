@@ -1,6 +1,7 @@
 package walk
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/doc"
@@ -12,17 +13,23 @@ import (
 	"strings"
 	"unsafe"
 
+	"github.com/gobuffalo/flect"
 	"sigs.k8s.io/controller-tools/pkg/loader"
 
 	"github.com/kure-sh/ingest-go/config"
 	"github.com/kure-sh/ingest-go/spec"
 )
 
-const metav1 = "k8s.io/apimachinery/pkg/apis/meta/v1"
+const (
+	metav1 = "k8s.io/apimachinery/pkg/apis/meta/v1"
+	intstr = "k8s.io/apimachinery/pkg/util/intstr"
+)
 
 type GeneratorContext struct {
 	Config   *config.Config
 	Packages map[string]*Package
+	// Bundles of dependencies, indexed by name
+	Bundles map[string]*spec.Bundle
 }
 
 func NewGeneratorContext(conf *config.Config, pkgs []*Package) *GeneratorContext {
@@ -63,11 +70,14 @@ func NewGenerator(gctx *GeneratorContext, target *Package) *Generator {
 		GeneratorContext: gctx,
 		Target:           target,
 		Export:           export,
-		comment:          ReadComment(target.doc.Doc),
+		comment:          target.comment,
 		comments:         scanPackageComments(target.pkg),
 		decls:            target.Declarations(),
 		deps:             make(map[string]*config.Dependency),
 	}
+	if export.Order == config.DefinitionOrderSource {
+		gen.decls.SortBySource()
+	}
 
 	return gen
 }
@@ -76,6 +86,11 @@ func (g *Generator) Generate() (*spec.APIGroupVersion, error) {
 	if g.Target.Group == nil {
 		return nil, fmt.Errorf("API group not defined for %s", g.Target.Path())
 	}
+	switch g.Export.Order {
+	case "", config.DefinitionOrderName, config.DefinitionOrderSource:
+	default:
+		return nil, fmt.Errorf("unknown definition order %q", g.Export.Order)
+	}
 
 	gv := &spec.APIGroupVersion{
 		APIVersion: "spec.kure.sh/v1alpha1",
@@ -94,12 +109,7 @@ func (g *Generator) Generate() (*spec.APIGroupVersion, error) {
 	}
 	gv.Definitions = defs
 
-	for _, dep := range g.deps {
-		gv.Dependencies = append(gv.Dependencies, spec.APIDependency{
-			Package: dep.Name,
-			Version: dep.Version,
-		})
-	}
+	gv.Dependencies = append(gv.Dependencies, sortedDependencies(g.deps)...)
 
 	return gv, nil
 }
@@ -135,25 +145,7 @@ func (g *Generator) Definitions() ([]spec.Definition, error) {
 }
 
 func (g *Generator) included(name string) bool {
-	if len(g.Export.Exclude) > 0 {
-		for _, n := range g.Export.Exclude {
-			if name == n {
-				return false
-			}
-		}
-	}
-
-	if len(g.Export.Include) > 0 {
-		for _, n := range g.Export.Include {
-			if name == n {
-				return true
-			}
-		}
-
-		return false
-	}
-
-	return true
+	return g.Export.Includes(name)
 }
 
 func (g *Generator) underlying(named *types.Named) types.Type {
@@ -194,15 +186,22 @@ func (g *Generator) definition(name string, t types.Type, w types.Type, d *doc.T
 	if d != nil {
 		comment = ReadComment(d.Doc)
 	}
+	comment.AddMarkers(g.markerComments(p)) // look for markers above the doc comment
 
 	if comment.Marker("protobuf") == "false" {
 		return nil, nil
 	}
 
+	rules, err := validationRules(comment)
+	if err != nil {
+		return nil, err
+	}
+
 	meta := spec.DefinitionMeta{
 		Name:        name,
 		Description: comment.Text,
 		Deprecated:  comment.Deprecated(),
+		Validations: rules,
 	}
 
 	typeDef, err := g.value(t, w, d, p)
@@ -215,6 +214,9 @@ func (g *Generator) definition(name string, t types.Type, w types.Type, d *doc.T
 
 func (g *Generator) value(t types.Type, w types.Type, d *doc.Type, p token.Pos) (r *spec.Type, err error) {
 	comment := ReadComment(d.Doc)
+	if p.IsValid() {
+		comment.AddMarkers(g.markerComments(p)) // look for markers above the doc comment
+	}
 
 	switch t := t.(type) {
 	case *types.Basic:
@@ -241,6 +243,16 @@ func (g *Generator) value(t types.Type, w types.Type, d *doc.Type, p token.Pos)
 		return
 	}
 
+	// the markers of a pointer apply to the value pointed to
+	if _, ok := t.(*types.Pointer); !ok {
+		if err = constrain(t, r, comment); err != nil {
+			return nil, err
+		}
+		if err = applyListSemantics(r, comment); err != nil {
+			return nil, err
+		}
+	}
+
 	if _, optional := r.Variant.(*spec.OptionalType); !optional && comment.Marker("+nullable") == "true" {
 		r = &spec.Type{
 			Variant: &spec.OptionalType{Value: *r},
@@ -411,6 +423,11 @@ func (g *Generator) structType(t *types.Struct, w types.Type, d *doc.Type, p tok
 		if tag != "" {
 			json := reflect.StructTag(tag).Get("json")
 
+			if array, ok := nonOptional(vt).Variant.(*spec.ArrayType); ok {
+				array.PatchStrategy = reflect.StructTag(tag).Get("patchStrategy")
+				array.PatchMergeKey = reflect.StructTag(tag).Get("patchMergeKey")
+			}
+
 			if json != "" {
 				parts := strings.Split(json, ",")
 				name = parts[0]
@@ -432,14 +449,27 @@ func (g *Generator) structType(t *types.Struct, w types.Type, d *doc.Type, p tok
 		} else {
 			comment := ReadComment(fdoc)
 
+			def, err := g.defaultValue(comment, *vt)
+			if err != nil {
+				pos := g.Target.pkg.Fset.Position(field.Pos())
+				return nil, fmt.Errorf("field %s (%s): %w", field.Name(), pos, err)
+			}
+
+			rules, err := validationRules(comment)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name(), err)
+			}
+
 			props = append(props, spec.Property{
 				PropertyMeta: spec.PropertyMeta{
 					DefinitionMeta: spec.DefinitionMeta{
 						Name:        name,
 						Description: comment.Text,
 						Deprecated:  comment.Deprecated(),
+						Validations: rules,
 					},
 					Required: g.fieldRequired(comment, omissible),
+					Default:  def,
 				},
 				Value: *vt,
 			})
@@ -451,18 +481,17 @@ func (g *Generator) structType(t *types.Struct, w types.Type, d *doc.Type, p tok
 			return nil, fmt.Errorf("resources cannot have inline fields")
 		}
 
-		for i, prop := range props {
-			if prop.Name == "spec" && !prop.Required {
-				props[i].Required = true
-			} else if prop.Name == "status" && prop.Required {
-				props[i].Required = false
-			}
+		requireSpec(props)
+
+		meta, err := g.resourceMeta(d.Name, comment, props)
+		if err != nil {
+			return nil, err
 		}
 
 		return &spec.Type{
 			Variant: &spec.ResourceType{
 				Properties: props,
-				Metadata:   g.resourceMeta(d.Name, comment),
+				Metadata:   meta,
 			},
 		}, nil
 	}
@@ -472,49 +501,44 @@ func (g *Generator) structType(t *types.Struct, w types.Type, d *doc.Type, p tok
 	}, nil
 }
 
-func (g *Generator) resourceMeta(kind string, comment Comment) spec.ResourceMeta {
-	resource := ""
-	scale := comment.Marker("kubebuilder:subresource:scale")
+func (g *Generator) resourceMeta(kind string, comment Comment, props []spec.Property) (spec.ResourceMeta, error) {
 	status := comment.Marker("kubebuilder:subresource:status")
-	for _, m := range comment.Markers {
-		if strings.HasPrefix(m, "kubebuilder:resource:") {
-			value := strings.TrimPrefix(m, "kubebuilder:resource:")
-
-			if resource == "" {
-				resource = value
-			} else {
-				resource += "," + value
-			}
-		}
-	}
 
 	name := ""
 	singularName := ""
 	scope := spec.ScopeNamespace
+	var shortNames, categories []string
 
-	if resource != "" {
-		parts := strings.Split(resource, ",")
+	for _, value := range comment.MarkerArgs("kubebuilder:resource") {
+		args, err := scanMarkerArgs(value)
+		if err != nil {
+			return spec.ResourceMeta{}, fmt.Errorf("invalid resource marker: %w", err)
+		}
 
-		for _, part := range parts {
-			kv := strings.SplitN(part, "=", 2)
-			if len(kv) != 2 {
-				continue
-			}
+		if path, ok := args["path"]; ok {
+			name = path
+		}
+		if singular, ok := args["singular"]; ok {
+			singularName = singular
+		}
+		switch args["scope"] {
+		case "Cluster", "cluster":
+			scope = spec.ScopeCluster
+		case "Namespaced", "namespaced", "namespace":
+			scope = spec.ScopeNamespace
+		}
 
-			switch kv[0] {
-			case "path":
-				name = kv[1]
-			case "singular":
-				singularName = kv[1]
-			case "scope":
-				switch kv[1] {
-				case "Cluster", "cluster":
-					scope = spec.ScopeCluster
-				case "Namespaced", "namespaced", "namespace":
-					scope = spec.ScopeNamespace
-				}
-			}
+		values, err := scanEnumValidation(args["shortName"])
+		if err != nil {
+			return spec.ResourceMeta{}, fmt.Errorf("invalid shortName: %w", err)
 		}
+		shortNames = append(shortNames, values...)
+
+		values, err = scanEnumValidation(args["categories"])
+		if err != nil {
+			return spec.ResourceMeta{}, fmt.Errorf("invalid categories: %w", err)
+		}
+		categories = append(categories, values...)
 	}
 	for _, m := range comment.Markers {
 		if strings.HasPrefix(m, "genclient:") && strings.Contains(m, "nonNamespaced") {
@@ -523,16 +547,143 @@ func (g *Generator) resourceMeta(kind string, comment Comment) spec.ResourceMeta
 		}
 	}
 
+	if name == "" {
+		name = comment.Marker("resourceName")
+	}
+	if name == "" {
+		name = resourceName(kind)
+	}
+	if singularName == "" {
+		singularName = strings.ToLower(kind)
+	}
+
+	columns, err := printerColumns(comment)
+	if err != nil {
+		return spec.ResourceMeta{}, err
+	}
+
+	subresources, err := g.subresources(kind, comment)
+	if err != nil {
+		return spec.ResourceMeta{}, err
+	}
+
+	// genclient types with a status have a status subresource, unless marked
+	// otherwise or never updated (e.g. the create-only TokenReview)
+	subresources.Status = status != ""
+	if !subresources.Status && comment.Marker("genclient") == "true" &&
+		comment.Marker("genclient:noStatus") == "" && hasProperty(props, "status") {
+		verbs, err := resourceVerbs(comment, false)
+		if err != nil {
+			return spec.ResourceMeta{}, err
+		}
+		subresources.Status = slices.Contains(verbs, "update")
+	}
+
+	verbs, err := resourceVerbs(comment, subresources.Status)
+	if err != nil {
+		return spec.ResourceMeta{}, err
+	}
+
+	deprecated := comment.Marker("kubebuilder:deprecatedversion") == "true"
+	warning := ""
+	for _, value := range comment.MarkerArgs("kubebuilder:deprecatedversion") {
+		args, err := scanMarkerArgs(value)
+		if err != nil {
+			return spec.ResourceMeta{}, fmt.Errorf("invalid deprecatedversion marker: %w", err)
+		}
+
+		deprecated = true
+		warning = args["warning"]
+	}
+
 	return spec.ResourceMeta{
 		Name:         name,
 		SingularName: singularName,
 		Kind:         kind,
 		Scope:        scope,
-		Subresources: spec.Subresources{
-			Status: status != "",
-			Scale:  scale != "",
-		},
+		Subresources: subresources,
+		Verbs:        verbs,
+
+		Storage:            comment.Marker("kubebuilder:storageversion") == "true",
+		Unserved:           comment.Marker("kubebuilder:unservedversion") == "true",
+		DeprecatedVersion:  deprecated,
+		DeprecationWarning: warning,
+
+		ShortNames:     shortNames,
+		Categories:     categories,
+		PrinterColumns: columns,
+	}, nil
+}
+
+// The spec of a resource is always required, and its status never is.
+func requireSpec(props []spec.Property) {
+	for i, prop := range props {
+		if prop.Name == "spec" && !prop.Required {
+			props[i].Required = true
+		} else if prop.Name == "status" && prop.Required {
+			props[i].Required = false
+		}
+	}
+}
+
+func hasProperty(props []spec.Property, name string) bool {
+	for _, prop := range props {
+		if prop.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Kinds whose resource names are not regular plurals (as in client-gen).
+var irregularResourceNames = map[string]string{
+	"Endpoints":                  "endpoints",
+	"EndpointSlice":              "endpointslices",
+	"SecurityContextConstraints": "securitycontextconstraints",
+}
+
+// Derive the plural resource name of a kind, as controller-gen does.
+func resourceName(kind string) string {
+	if name, ok := irregularResourceNames[kind]; ok {
+		return name
+	}
+
+	return strings.ToLower(flect.Pluralize(kind))
+}
+
+func printerColumns(comment Comment) ([]spec.PrinterColumn, error) {
+	var columns []spec.PrinterColumn
+
+	for _, value := range comment.MarkerArgs("kubebuilder:printcolumn") {
+		args, err := scanMarkerArgs(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid printcolumn marker: %w", err)
+		}
+
+		column := spec.PrinterColumn{
+			Name:        args["name"],
+			Type:        args["type"],
+			JSONPath:    args["JSONPath"],
+			Description: args["description"],
+			Format:      args["format"],
+		}
+		if column.Name == "" || column.Type == "" || column.JSONPath == "" {
+			return nil, fmt.Errorf("invalid printcolumn marker: name, type and JSONPath are required")
+		}
+
+		if priority := args["priority"]; priority != "" {
+			n, err := strconv.ParseInt(priority, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid printcolumn priority: %w", err)
+			}
+			column.Priority = int32(n)
+		}
+
+		columns = append(columns, column)
 	}
+
+	return columns, nil
 }
 
 func (g *Generator) fieldRequired(comment Comment, omissible bool) bool {
@@ -564,42 +715,15 @@ func (g *Generator) referenceType(t *types.Named, d *doc.Type) (*spec.Type, erro
 
 	targetPath := loader.NonVendorPath(n.Pkg().Path())
 
-	var scope *spec.ReferenceScope
-
 	if g.Target.Path() != targetPath {
-		if res := g.builtinReferenceType(targetPath, n.Name()); res != nil {
-			return res, nil
-		}
-
-		target := g.Config.ResolvePackage(targetPath)
-		if target == nil {
-			return nil, fmt.Errorf("undeclared package %s", targetPath)
-		}
-
-		export := target.Export()
-		var module *string
-		if export.Module != "" {
-			module = &export.Module
-		}
-
-		depName := target.Dependency()
-		scope = &spec.ReferenceScope{
-			Package: depName,
-			Group: spec.APIGroupIdentifier{
-				Module: module,
-				Name:   export.Group,
-			},
-			Version: export.Version,
+		if res, err := mappedType(g.Config, g.deps, targetPath, n.Name()); res != nil || err != nil {
+			return res, err
 		}
+	}
 
-		if depName != "" {
-			depPkg := g.Config.Dependency(depName)
-			if depPkg == nil {
-				return nil, fmt.Errorf("extern package %+v not a declared dependency", depName)
-			}
-
-			g.deps[depName] = depPkg
-		}
+	scope, err := g.referenceScope(targetPath)
+	if err != nil {
+		return nil, err
 	}
 
 	return &spec.Type{
@@ -612,56 +736,84 @@ func (g *Generator) referenceType(t *types.Named, d *doc.Type) (*spec.Type, erro
 	}, nil
 }
 
-func (g *Generator) builtinReferenceType(pkgPath, name string) *spec.Type {
-	meta := "meta"
+// Find the scope of a reference to a type in the package targetPath, which is
+// nil for the package being generated.
+func (g *Generator) referenceScope(targetPath string) (*spec.ReferenceScope, error) {
+	if g.Target.Path() == targetPath {
+		return nil, nil
+	}
 
-	switch {
-	case pkgPath == "k8s.io/apimachinery/pkg/runtime" && (name == "Object" || name == "RawExtension"):
-		return &spec.Type{Variant: &spec.UnknownType{}}
+	scope, dep, err := packageScope(g.Config, targetPath)
+	if dep != nil {
+		g.deps[dep.Name] = dep
+	}
 
-	case pkgPath == "k8s.io/apimachinery/pkg/util/intstr" && name == "IntOrString":
-		return &spec.Type{
-			Variant: &spec.UnionType{
-				Values: []spec.Type{
-					{Variant: &spec.IntegerType{Size: 32}},
-					{Variant: &spec.StringType{}},
-				},
-			},
-		}
+	return scope, err
+}
 
-	// TODO: this is kinda janky
-	case pkgPath == "k8s.io/apimachinery/pkg/api/resource" && (name == "Quantity" || name == "QuantityValue"):
-		return &spec.Type{
-			Variant: &spec.ReferenceType{
-				Target: spec.ReferenceTarget{
-					Scope: &spec.ReferenceScope{
-						Package: "kubernetes",
-						Group: spec.APIGroupIdentifier{
-							Module: &meta,
-							Name:   meta,
-						},
-						Version: "v1",
-					},
-					Name: "Quantity",
-				},
-			},
-		}
+// Find the scope of a reference to a type in another package, and the
+// dependency which provides it (if any).
+func packageScope(conf *config.Config, targetPath string) (*spec.ReferenceScope, *config.Dependency, error) {
+	target := conf.ResolvePackage(targetPath)
+	if target == nil {
+		return nil, nil, fmt.Errorf("undeclared package %s", targetPath)
+	}
 
-	case pkgPath == "time" && name == "Duration":
-		return &spec.Type{
-			Variant: &spec.StringType{
-				Format: "duration",
-			},
+	export := target.Export()
+	var module *string
+	if export.Module != "" {
+		module = &export.Module
+	}
+
+	depName := target.Dependency()
+	scope := &spec.ReferenceScope{
+		Package: depName,
+		Group: spec.APIGroupIdentifier{
+			Module: module,
+			Name:   export.Group,
+		},
+		Version: export.Version,
+	}
+
+	if depName == "" {
+		return scope, nil, nil
+	}
+
+	depPkg := conf.Dependency(depName)
+	if depPkg == nil {
+		return nil, nil, fmt.Errorf("extern package %s refers to dependency %q, which is not declared (add a [[dependency]] with name = %q and its version to kure.toml)", targetPath, depName, depName)
+	}
+
+	return scope, depPkg, nil
+}
+
+// The type a Go type is mapped to (see config.TypeMapping), if any, recording
+// the dependencies it refers to.
+func mappedType(conf *config.Config, deps map[string]*config.Dependency, pkgPath, name string) (*spec.Type, error) {
+	t, err := conf.MapType(pkgPath, name)
+	if t == nil {
+		return nil, err
+	}
+
+	var errs []error
+	visitReferences(t, func(ref *spec.ReferenceType) {
+		scope := ref.Target.Scope
+		if scope == nil || scope.Package == "" {
+			return
 		}
-	case pkgPath == "time" && name == "Time":
-		return &spec.Type{
-			Variant: &spec.StringType{
-				Format: "date-time",
-			},
+
+		dep := conf.Dependency(scope.Package)
+		if dep == nil {
+			errs = append(errs, fmt.Errorf("type mapping of %s.%s: undeclared dependency %q", pkgPath, name, scope.Package))
+			return
 		}
+		deps[dep.Name] = dep
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
-	return nil
+	return t, nil
 }
 
 func (g *Generator) arrayType(t *types.Slice, d *doc.Type) (*spec.Type, error) {
@@ -673,7 +825,7 @@ func (g *Generator) arrayType(t *types.Slice, d *doc.Type) (*spec.Type, error) {
 		}, nil
 	}
 
-	value, err := g.value(et, nil, d, token.NoPos)
+	value, err := g.value(et, nil, itemsDoc(d), token.NoPos)
 	if value == nil {
 		return nil, err
 	}
@@ -692,7 +844,7 @@ func (g *Generator) mapType(t *types.Map, d *doc.Type) (*spec.Type, error) {
 
 	et := t.Elem()
 
-	value, err := g.value(et, nil, d, token.NoPos)
+	value, err := g.value(et, nil, &doc.Type{}, token.NoPos)
 	if err != nil {
 		return nil, err
 	}
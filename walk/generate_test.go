@@ -0,0 +1,178 @@
+package walk
+
+import (
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// A generator of a package with no sources, for the parts of generation which
+// only read markers.
+func testGenerator(path string) *Generator {
+	pkg := &Package{pkg: &packages.Package{Types: types.NewPackage(path, "v1")}}
+
+	return &Generator{Target: pkg}
+}
+
+func TestResourceMetaStatus(t *testing.T) {
+	withStatus := []spec.Property{
+		{PropertyMeta: spec.PropertyMeta{DefinitionMeta: spec.DefinitionMeta{Name: "spec"}}},
+		{PropertyMeta: spec.PropertyMeta{DefinitionMeta: spec.DefinitionMeta{Name: "status"}}},
+	}
+
+	tests := []struct {
+		name    string
+		markers []string
+		props   []spec.Property
+		status  bool
+		verbs   []string
+	}{
+		{
+			name:    "genclient",
+			markers: []string{"genclient"},
+			props:   withStatus,
+			status:  true,
+			verbs:   standardVerbs,
+		},
+		{
+			name:    "create only",
+			markers: []string{"genclient", "genclient:nonNamespaced", "genclient:onlyVerbs=create"},
+			props:   withStatus,
+			verbs:   []string{"create"},
+		},
+		{
+			name:    "noStatus",
+			markers: []string{"genclient", "genclient:noStatus"},
+			props:   withStatus,
+			verbs:   []string{"create", "update", "delete", "deleteCollection", "get", "list", "watch", "patch", "apply"},
+		},
+		{
+			name:    "no status property",
+			markers: []string{"genclient"},
+			props:   withStatus[:1],
+			verbs:   []string{"create", "update", "delete", "deleteCollection", "get", "list", "watch", "patch", "apply"},
+		},
+		{
+			name:    "kubebuilder",
+			markers: []string{"kubebuilder:subresource:status"},
+			props:   withStatus,
+			status:  true,
+			verbs:   standardVerbs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGenerator("k8s.io/api/authentication/v1")
+
+			meta, err := g.resourceMeta("TokenReview", Comment{Markers: tt.markers}, tt.props)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if meta.Subresources.Status != tt.status {
+				t.Errorf("status subresource %v, want %v", meta.Subresources.Status, tt.status)
+			}
+			if !slices.Equal(meta.Verbs, tt.verbs) {
+				t.Errorf("verbs %v, want %v", meta.Verbs, tt.verbs)
+			}
+		})
+	}
+}
+
+func TestPackageScopeUndeclaredKubernetes(t *testing.T) {
+	conf := &config.Config{Name: "widgets"}
+
+	_, _, err := packageScope(conf, metav1)
+	if err == nil || !strings.Contains(err.Error(), `name = "kubernetes"`) {
+		t.Fatalf("error %v, want one naming the kubernetes dependency to declare", err)
+	}
+
+	conf.Dependencies = []config.Dependency{{Name: config.KubernetesDependency, Version: "1.29"}}
+
+	scope, dep, err := packageScope(conf, metav1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dep == nil || dep.Version != "1.29" || scope.Package != config.KubernetesDependency {
+		t.Errorf("scope %+v, dependency %+v", scope, dep)
+	}
+}
+
+func TestSubresourcesUndeclaredType(t *testing.T) {
+	off := false
+	g := testGenerator(corev1)
+	g.GeneratorContext = &GeneratorContext{Config: &config.Config{Name: "kubernetes", BuiltinExterns: &off}}
+
+	comment := Comment{Markers: []string{
+		"genclient:method=CreateToken,verb=create,subresource=token,input=k8s.io/api/authentication/v1.TokenRequest,result=k8s.io/api/authentication/v1.TokenRequest",
+	}}
+
+	sub, err := g.subresources("ServiceAccount", comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sub.Additional) != 1 {
+		t.Fatalf("subresources %+v, want token", sub.Additional)
+	}
+	token := sub.Additional[0]
+	if token.Name != "token" || token.Verb != "create" || token.Input != nil || token.Output != nil {
+		t.Errorf("subresource %+v, want token without types", token)
+	}
+}
+
+func TestResourceMetaShortNames(t *testing.T) {
+	g := testGenerator("example.com/widgets/v1")
+	comment := Comment{Markers: []string{
+		"kubebuilder:resource:path=widgets,shortName={wd,wdg},scope=Cluster",
+		"kubebuilder:resource:categories={all,widgets}",
+	}}
+
+	meta, err := g.resourceMeta("Widget", comment, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(meta.ShortNames, []string{"wd", "wdg"}) {
+		t.Errorf("short names %q, want wd and wdg", meta.ShortNames)
+	}
+	if !slices.Equal(meta.Categories, []string{"all", "widgets"}) {
+		t.Errorf("categories %q, want all and widgets", meta.Categories)
+	}
+	if meta.Name != "widgets" || meta.Scope != spec.ScopeCluster {
+		t.Errorf("resource %s scoped %s, want cluster-scoped widgets", meta.Name, meta.Scope)
+	}
+}
+
+func TestMappedTypeQuantity(t *testing.T) {
+	const resource = "k8s.io/apimachinery/pkg/api/resource"
+	deps := map[string]*config.Dependency{}
+
+	off := false
+	conf := &config.Config{Name: "widgets", BuiltinExterns: &off}
+	if mapped, err := mappedType(conf, deps, resource, "Quantity"); mapped != nil || err != nil {
+		t.Errorf("mapped to %+v (%v) without builtin externs", mapped, err)
+	}
+
+	conf = &config.Config{
+		Name:         "widgets",
+		Dependencies: []config.Dependency{{Name: config.KubernetesDependency, Version: "1.29"}},
+	}
+	mapped, err := mappedType(conf, deps, resource, "Quantity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref, ok := mapped.Variant.(*spec.ReferenceType); !ok || ref.Target.Name != "Quantity" {
+		t.Errorf("mapped to %+v, want a reference to meta Quantity", mapped)
+	}
+	if deps[config.KubernetesDependency] == nil {
+		t.Error("kubernetes dependency not recorded")
+	}
+}
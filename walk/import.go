@@ -0,0 +1,336 @@
+package walk
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// Converts JSON schemas (of CRDs or OpenAPI documents) to kure types. Object
+// schemas are lifted into definitions, named after the path to them (e.g.
+// FooSpecTemplate), unless excluded by the export.
+type schemaImporter struct {
+	export *config.Export
+	// Resolve a $ref to a type; references are unsupported if nil
+	ref func(ref string) (*spec.Type, error)
+	// Find the type a Go type is mapped to (see mappedType); type mappings
+	// are unsupported if nil
+	mapType func(pkgPath, name string) (*spec.Type, error)
+
+	defs  []spec.Definition
+	names map[string]bool
+}
+
+func newSchemaImporter(export *config.Export, ref func(string) (*spec.Type, error)) *schemaImporter {
+	return &schemaImporter{export: export, ref: ref, names: make(map[string]bool)}
+}
+
+func (im *schemaImporter) mappedType(pkgPath, name string) (*spec.Type, error) {
+	if im.mapType == nil {
+		return nil, fmt.Errorf("unsupported type %s.%s", pkgPath, name)
+	}
+
+	t, err := im.mapType(pkgPath, name)
+	if t == nil && err == nil {
+		return nil, fmt.Errorf("no type mapping of %s.%s", pkgPath, name)
+	}
+
+	return t, err
+}
+
+func (im *schemaImporter) typ(name string, s *Schema) (*spec.Type, error) {
+	t, err := im.value(name, s)
+	if err != nil || !s.Nullable {
+		return t, err
+	}
+
+	return &spec.Type{Variant: &spec.OptionalType{Value: *t}}, nil
+}
+
+func (im *schemaImporter) value(name string, s *Schema) (*spec.Type, error) {
+	if s.Ref != "" {
+		if im.ref == nil {
+			return nil, fmt.Errorf("unsupported reference %s", s.Ref)
+		}
+		return im.ref(s.Ref)
+	}
+
+	// OpenAPI v3 wraps references to describe them
+	if len(s.AllOf) == 1 && s.Type == "" && len(s.Properties) == 0 {
+		return im.typ(name, s.AllOf[0])
+	}
+
+	switch {
+	case s.XIntOrString:
+		return im.mappedType(intstr, "IntOrString")
+	case s.XEmbeddedResource:
+		return &spec.Type{Variant: &spec.UnknownType{}}, nil
+	case len(s.OneOf) > 0:
+		return im.union(name, s.OneOf)
+	case len(s.AnyOf) > 0:
+		return im.union(name, s.AnyOf)
+	}
+
+	switch s.Type {
+	case "string":
+		t := &spec.StringType{
+			Format:    s.Format,
+			MinLength: s.MinLength,
+			MaxLength: s.MaxLength,
+			Pattern:   s.Pattern,
+		}
+		for _, value := range s.Enum {
+			if str, ok := value.(string); ok {
+				t.Enum = append(t.Enum, str)
+			}
+		}
+		return &spec.Type{Variant: t}, nil
+
+	case "integer":
+		t := &spec.IntegerType{MultipleOf: int64Bound(s.MultipleOf)}
+		switch s.Format {
+		case "int32":
+			t.Size = 32
+		case "int64":
+			t.Size = 64
+		}
+
+		min, max := s.Minimum, s.Maximum
+		t.ExclusiveMinimum = exclusiveBound(s.ExclusiveMinimum, &min)
+		t.ExclusiveMaximum = exclusiveBound(s.ExclusiveMaximum, &max)
+		t.Minimum, t.Maximum = int64Bound(min), int64Bound(max)
+		return &spec.Type{Variant: t}, nil
+
+	case "number":
+		t := &spec.FloatType{MultipleOf: s.MultipleOf}
+		switch s.Format {
+		case "float":
+			t.Size = 32
+		case "double":
+			t.Size = 64
+		}
+
+		t.Minimum, t.Maximum = s.Minimum, s.Maximum
+		t.ExclusiveMinimum = exclusiveBound(s.ExclusiveMinimum, &t.Minimum)
+		t.ExclusiveMaximum = exclusiveBound(s.ExclusiveMaximum, &t.Maximum)
+		return &spec.Type{Variant: t}, nil
+
+	case "boolean":
+		return &spec.Type{Variant: &spec.BooleanType{}}, nil
+
+	case "array":
+		values := &spec.Type{Variant: &spec.UnknownType{}}
+		if s.Items != nil {
+			var err error
+			if values, err = im.typ(flect.Singularize(name), s.Items); err != nil {
+				return nil, err
+			}
+		}
+
+		return &spec.Type{
+			Variant: &spec.ArrayType{
+				Values:      *values,
+				MinItems:    s.MinItems,
+				MaxItems:    s.MaxItems,
+				UniqueItems: s.UniqueItems,
+				ListType:    s.XListType,
+				ListMapKeys: s.XListMapKeys,
+
+				PatchStrategy: s.XPatchStrategy,
+				PatchMergeKey: s.XPatchMergeKey,
+			},
+		}, nil
+
+	case "object", "":
+		if len(s.Properties) > 0 {
+			return im.object(name, s)
+		}
+
+		if s.AdditionalProperties == nil || s.AdditionalProperties.never {
+			return &spec.Type{Variant: &spec.UnknownType{}}, nil
+		}
+
+		values, err := im.typ(flect.Singularize(name), s.AdditionalProperties)
+		if err != nil {
+			return nil, err
+		}
+
+		return &spec.Type{
+			Variant: &spec.MapType{
+				Values:        *values,
+				MinProperties: s.MinProperties,
+				MaxProperties: s.MaxProperties,
+				MapType:       s.XMapType,
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported schema type %q", s.Type)
+}
+
+// A union, or an optional value as JSON Schema expresses it.
+func (im *schemaImporter) union(name string, schemas []*Schema) (*spec.Type, error) {
+	var values []spec.Type
+	nullable := false
+
+	for _, s := range schemas {
+		if s.Type == "null" {
+			nullable = true
+			continue
+		}
+
+		t, err := im.typ(name, s)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, *t)
+	}
+
+	t := &spec.Type{Variant: &spec.UnionType{Values: values}}
+	if len(values) == 1 {
+		t = &values[0]
+	}
+	if nullable {
+		t = &spec.Type{Variant: &spec.OptionalType{Value: *t}}
+	}
+
+	return t, nil
+}
+
+// Lift an object schema into a definition, referring to it.
+func (im *schemaImporter) object(name string, s *Schema) (*spec.Type, error) {
+	if !im.export.Includes(name) {
+		props, err := im.properties(name, s)
+		if err != nil {
+			return nil, err
+		}
+
+		return &spec.Type{Variant: &spec.ObjectType{Properties: props}}, nil
+	}
+
+	name = im.reserve(name)
+	if err := im.define(name, s); err != nil {
+		return nil, err
+	}
+
+	return &spec.Type{
+		Variant: &spec.ReferenceType{Target: spec.ReferenceTarget{Name: name}},
+	}, nil
+}
+
+// Add a definition of a schema, under a reserved name.
+func (im *schemaImporter) define(name string, s *Schema) error {
+	// define the object before the objects of its properties
+	i := len(im.defs)
+	im.defs = append(im.defs, spec.Definition{
+		DefinitionMeta: spec.DefinitionMeta{
+			Name:        name,
+			Description: s.Description,
+			Validations: s.XValidations,
+		},
+	})
+
+	if len(s.Properties) == 0 {
+		t, err := im.typ(name, s)
+		if err != nil {
+			return err
+		}
+
+		im.defs[i].Value = *t
+		return nil
+	}
+
+	props, err := im.properties(name, s)
+	if err != nil {
+		return err
+	}
+
+	im.defs[i].Value = spec.Type{Variant: &spec.ObjectType{Properties: props}}
+	return nil
+}
+
+func (im *schemaImporter) properties(parent string, s *Schema) ([]spec.Property, error) {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	props := make([]spec.Property, 0, len(names))
+	for _, name := range names {
+		ps := s.Properties[name]
+
+		t, err := im.typ(parent+exportedName(name), ps)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", name, err)
+		}
+
+		prop := spec.Property{
+			PropertyMeta: spec.PropertyMeta{
+				DefinitionMeta: spec.DefinitionMeta{
+					Name:        name,
+					Description: ps.Description,
+				},
+				Required: slices.Contains(s.Required, name),
+				Default:  ps.Default,
+			},
+			Value: *t,
+		}
+		// the rules of lifted objects belong to their definitions
+		if _, lifted := t.Variant.(*spec.ReferenceType); !lifted {
+			prop.Validations = ps.XValidations
+		}
+
+		props = append(props, prop)
+	}
+
+	return props, nil
+}
+
+// Claim a definition name, numbering it if already taken.
+func (im *schemaImporter) reserve(name string) string {
+	unique := name
+	for i := 2; im.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	im.names[unique] = true
+	return unique
+}
+
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// Read an exclusive bound: a boolean qualifying the bound (OpenAPI v3.0), or
+// the bound itself (JSON Schema).
+func exclusiveBound(v any, bound **float64) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		*bound = &v
+		return true
+	}
+
+	return false
+}
+
+func int64Bound(f *float64) *int64 {
+	if f == nil {
+		return nil
+	}
+
+	n := int64(*f)
+	return &n
+}
@@ -0,0 +1,34 @@
+package walk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+func TestImportIntOrString(t *testing.T) {
+	conf := &config.Config{Name: "widgets"}
+	gen := newCRDGroupVersion(conf, &config.Export{Group: "example.com", Version: "v1"})
+
+	got, err := gen.importer.typ("port", &Schema{XIntOrString: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := conf.MapType(intstr, "IntOrString")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want the builtin mapping %#v", got.Variant, want.Variant)
+	}
+
+	// a mapping in kure.toml applies to imported schemas too
+	conf.TypeMappings = []config.TypeMapping{{Path: intstr, Name: "IntOrString", Type: map[string]any{"type": "string"}}}
+
+	got, err = gen.importer.typ("port", &Schema{XIntOrString: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Variant.(*spec.StringType); !ok {
+		t.Errorf("got %#v, want the configured string", got.Variant)
+	}
+}
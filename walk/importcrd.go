@@ -0,0 +1,329 @@
+package walk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// Read the CustomResourceDefinitions of YAML or JSON manifests, given as files
+// or directories (searched recursively).
+func LoadCRDs(paths ...string) ([]*CustomResourceDefinition, error) {
+	var crds []*CustomResourceDefinition
+
+	err := readFiles(paths, []string{".yaml", ".yml", ".json"}, func(path string) error {
+		found, err := readCRDs(path)
+		crds = append(crds, found...)
+		return err
+	})
+
+	return crds, err
+}
+
+// Read each file, and each file with one of the extensions in directories
+// (searched recursively).
+func readFiles(paths []string, exts []string, read func(path string) error) error {
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (path != root && !slices.Contains(exts, filepath.Ext(path))) {
+				return nil
+			}
+
+			if err := read(path); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readCRDs(filename string) ([]*CustomResourceDefinition, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var crds []*CustomResourceDefinition
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var doc any
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		obj, ok := jsonValue(doc).(map[string]any)
+		if !ok || obj["kind"] != "CustomResourceDefinition" {
+			continue
+		}
+		if obj["apiVersion"] != "apiextensions.k8s.io/v1" {
+			return nil, fmt.Errorf("unsupported CustomResourceDefinition version %v", obj["apiVersion"])
+		}
+
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		var crd CustomResourceDefinition
+		if err := json.Unmarshal(data, &crd); err != nil {
+			return nil, err
+		}
+
+		crds = append(crds, &crd)
+	}
+
+	return crds, nil
+}
+
+// Convert a decoded YAML value for encoding/json, which requires string keys.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = jsonValue(value)
+		}
+		return m
+
+	case []any:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+		return v
+	}
+
+	return v
+}
+
+// A group-version being generated from CustomResourceDefinitions.
+type crdGroupVersion struct {
+	gv       *spec.APIGroupVersion
+	importer *schemaImporter
+	deps     map[string]*config.Dependency
+}
+
+// Generate a bundle from CustomResourceDefinitions, with a definition for
+// each resource and for each object schema within it. Group-versions are
+// exported as declared in kure.toml, or else in full.
+func GenerateCRDBundle(gctx *GeneratorContext, crds []*CustomResourceDefinition) (*spec.Bundle, error) {
+	conf := gctx.Config
+
+	var gvs []*crdGroupVersion
+	index := make(map[string]*crdGroupVersion)
+
+	for _, crd := range crds {
+		for i := range crd.Spec.Versions {
+			version := &crd.Spec.Versions[i]
+
+			k := crd.Spec.Group + "/" + version.Name
+			gen := index[k]
+			if gen == nil {
+				gen = newCRDGroupVersion(conf, crdExport(conf, crd.Spec.Group, version.Name))
+				index[k] = gen
+				gvs = append(gvs, gen)
+			}
+
+			if err := gen.resource(conf, crd, version); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", crd.Metadata.Name, version.Name, err)
+			}
+		}
+	}
+
+	generated := make([]*spec.APIGroupVersion, len(gvs))
+	for i, gen := range gvs {
+		gen.gv.Definitions = gen.importer.defs
+		gen.gv.Dependencies = append(gen.gv.Dependencies, sortedDependencies(gen.deps)...)
+		generated[i] = gen.gv
+	}
+
+	return assembleBundle(gctx, generated)
+}
+
+func sortedDependencies(deps map[string]*config.Dependency) []spec.APIDependency {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sorted := make([]spec.APIDependency, len(names))
+	for i, name := range names {
+		sorted[i] = spec.APIDependency{Package: name, Version: deps[name].Version}
+	}
+
+	return sorted
+}
+
+// Find the export of a group-version, declaring one if not configured.
+func crdExport(conf *config.Config, group, version string) *config.Export {
+	for _, export := range conf.Exports {
+		if export.Group == group && export.Version == version {
+			return export
+		}
+	}
+
+	return conf.AddExport(config.Export{Group: group, Version: version})
+}
+
+func newCRDGroupVersion(conf *config.Config, export *config.Export) *crdGroupVersion {
+	var module *string
+	if export.Module != "" {
+		module = &export.Module
+	}
+
+	gen := &crdGroupVersion{
+		gv: &spec.APIGroupVersion{
+			APIVersion: spec.APIVersion,
+			Kind:       "APIGroupVersion",
+
+			API:     conf.Name,
+			Group:   spec.APIGroupIdentifier{Module: module, Name: export.Group},
+			Version: export.Version,
+
+			Dependencies: []spec.APIDependency{},
+		},
+		importer: newSchemaImporter(export, nil),
+		deps:     make(map[string]*config.Dependency),
+	}
+	gen.importer.mapType = func(pkgPath, name string) (*spec.Type, error) {
+		return mappedType(conf, gen.deps, pkgPath, name)
+	}
+
+	return gen
+}
+
+func (gen *crdGroupVersion) resource(conf *config.Config, crd *CustomResourceDefinition, version *CustomResourceDefinitionVersion) error {
+	names := crd.Spec.Names
+	im := gen.importer
+
+	if !im.export.Includes(names.Kind) {
+		return nil
+	}
+
+	s := version.Schema.OpenAPIV3Schema
+	if s == nil {
+		return fmt.Errorf("no schema")
+	}
+	if im.names[names.Kind] {
+		return fmt.Errorf("duplicate definition %s", names.Kind)
+	}
+	im.reserve(names.Kind)
+
+	i := len(im.defs)
+	im.defs = append(im.defs, spec.Definition{
+		DefinitionMeta: spec.DefinitionMeta{
+			Name:        names.Kind,
+			Description: s.Description,
+			Validations: s.XValidations,
+		},
+	})
+
+	// the type identifiers are implied, and metadata is always an ObjectMeta
+	body := *s
+	body.Properties = make(map[string]*Schema, len(s.Properties))
+	for name, ps := range s.Properties {
+		switch name {
+		case "apiVersion", "kind", "metadata":
+		default:
+			body.Properties[name] = ps
+		}
+	}
+
+	props, err := im.properties(names.Kind, &body)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := s.Properties["metadata"]; ok {
+		scope, dep, err := packageScope(conf, metav1)
+		if err != nil {
+			return fmt.Errorf("metadata: %w", err)
+		}
+		if dep != nil {
+			gen.deps[dep.Name] = dep
+		}
+
+		metadata := spec.Property{
+			PropertyMeta: spec.PropertyMeta{
+				DefinitionMeta: spec.DefinitionMeta{Name: "metadata"},
+			},
+			Value: spec.Type{
+				Variant: &spec.ReferenceType{
+					Target: spec.ReferenceTarget{Scope: scope, Name: "ObjectMeta"},
+				},
+			},
+		}
+		props = append([]spec.Property{metadata}, props...)
+	}
+
+	requireSpec(props)
+
+	scope := spec.ScopeNamespace
+	if crd.Spec.Scope == "Cluster" {
+		scope = spec.ScopeCluster
+	}
+
+	var subresources spec.Subresources
+	if sub := version.Subresources; sub != nil {
+		subresources.Status = sub.Status != nil
+		subresources.Scale = sub.Scale
+	}
+
+	verbs, err := resourceVerbs(Comment{}, subresources.Status)
+	if err != nil {
+		return err
+	}
+
+	meta := spec.ResourceMeta{
+		Name:         names.Plural,
+		SingularName: names.Singular,
+		Kind:         names.Kind,
+		Scope:        scope,
+		Subresources: subresources,
+		Verbs:        verbs,
+
+		Storage:           version.Storage,
+		Unserved:          !version.Served,
+		DeprecatedVersion: version.Deprecated,
+
+		ShortNames:     names.ShortNames,
+		Categories:     names.Categories,
+		PrinterColumns: version.AdditionalPrinterColumns,
+	}
+	if version.DeprecationWarning != nil {
+		meta.DeprecationWarning = *version.DeprecationWarning
+	}
+	if meta.SingularName == "" {
+		meta.SingularName = strings.ToLower(names.Kind)
+	}
+
+	im.defs[i].Value = spec.Type{
+		Variant: &spec.ResourceType{Properties: props, Metadata: meta},
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+const widgetCRD = `# not a CRD
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+    singular: widget
+  scope: Cluster
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          apiVersion: {type: string}
+          kind: {type: string}
+          metadata: {type: object}
+          spec:
+            type: object
+            required: [size]
+            properties:
+              size: {type: integer, format: int32, minimum: 1}
+              port:
+                x-kubernetes-int-or-string: true
+              parts:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    name: {type: string}
+`
+
+// Find a property of an object or resource definition.
+func definitionProperty(t *testing.T, gv *spec.APIGroupVersion, def, prop string) *spec.Property {
+	t.Helper()
+
+	for _, d := range gv.Definitions {
+		if d.Name != def {
+			continue
+		}
+
+		var props []spec.Property
+		switch v := d.Value.Variant.(type) {
+		case *spec.ObjectType:
+			props = v.Properties
+		case *spec.ResourceType:
+			props = v.Properties
+		}
+		for i := range props {
+			if props[i].Name == prop {
+				return &props[i]
+			}
+		}
+	}
+
+	t.Fatalf("no property %s.%s", def, prop)
+	return nil
+}
+
+func definitionNames(gv *spec.APIGroupVersion) []string {
+	names := make([]string, len(gv.Definitions))
+	for i, def := range gv.Definitions {
+		names[i] = def.Name
+	}
+
+	return names
+}
+
+func TestGenerateCRDBundle(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "widgets.yaml"), []byte(widgetCRD), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	crds, err := LoadCRDs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crds) != 1 {
+		t.Fatalf("loaded %d CRDs", len(crds))
+	}
+
+	conf := &config.Config{
+		Name:         "widgets",
+		Dependencies: []config.Dependency{{Name: config.KubernetesDependency, Version: "1.29"}},
+	}
+	bundle, err := GenerateCRDBundle(NewGeneratorContext(conf, nil), crds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Versions) != 1 {
+		t.Fatalf("generated %d group-versions", len(bundle.Versions))
+	}
+
+	gv := bundle.Versions[0]
+	if gv.Group.Name != "example.com" || gv.Version != "v1" {
+		t.Errorf("group-version %s/%s", gv.Group.Name, gv.Version)
+	}
+	if want := []spec.APIDependency{{Package: "kubernetes", Version: "1.29"}}; !reflect.DeepEqual(gv.Dependencies, want) {
+		t.Errorf("dependencies %v, want %v", gv.Dependencies, want)
+	}
+	if got, want := definitionNames(gv), []string{"Widget", "WidgetSpec", "WidgetSpecPart"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("definitions %v, want %v", got, want)
+	}
+
+	res, ok := gv.Definitions[0].Value.Variant.(*spec.ResourceType)
+	if !ok {
+		t.Fatalf("Widget is a %s", gv.Definitions[0].Value.Variant.Variant())
+	}
+	if meta := res.Metadata; meta.Name != "widgets" || meta.Scope != spec.ScopeCluster || !meta.Subresources.Status || !meta.Storage {
+		t.Errorf("metadata %+v", meta)
+	}
+
+	metadata := definitionProperty(t, gv, "Widget", "metadata")
+	if ref, ok := metadata.Value.Variant.(*spec.ReferenceType); !ok || ref.Target.Name != "ObjectMeta" || ref.Target.Scope.Package != "kubernetes" {
+		t.Errorf("metadata is %#v", metadata.Value.Variant)
+	}
+
+	size := definitionProperty(t, gv, "WidgetSpec", "size")
+	if want := (&spec.IntegerType{Size: 32, Minimum: int64p(1)}); !size.Required || !reflect.DeepEqual(size.Value.Variant, want) {
+		t.Errorf("size is %#v (required %v), want %#v", size.Value.Variant, size.Required, want)
+	}
+
+	port := definitionProperty(t, gv, "WidgetSpec", "port")
+	if _, ok := port.Value.Variant.(*spec.UnionType); !ok {
+		t.Errorf("port is %#v", port.Value.Variant)
+	}
+}
+
+func TestLoadCRDsUnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.yaml")
+	data := "apiVersion: apiextensions.k8s.io/v1beta1\nkind: CustomResourceDefinition\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadCRDs(path); err == nil {
+		t.Error("expected an error for a v1beta1 CustomResourceDefinition")
+	}
+}
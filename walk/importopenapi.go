@@ -0,0 +1,488 @@
+package walk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// An OpenAPI document served by the Kubernetes API server: /openapi/v2
+// (swagger) or /openapi/v3/apis/<group>/<version>.
+type OpenAPIDump struct {
+	Swagger string `json:"swagger,omitempty"`
+	OpenAPI string `json:"openapi,omitempty"`
+
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+
+	// Schemas of a swagger document
+	Definitions map[string]*Schema `json:"definitions,omitempty"`
+	// Schemas of an OpenAPI v3 document
+	Components OpenAPIComponents `json:"components"`
+}
+
+func (d *OpenAPIDump) schemas() map[string]*Schema {
+	if d.Swagger != "" {
+		return d.Definitions
+	}
+
+	return d.Components.Schemas
+}
+
+// An operation on a path, as annotated by the API server.
+type openAPIOperation struct {
+	Action string            `json:"x-kubernetes-action"`
+	GVK    *GroupVersionKind `json:"x-kubernetes-group-version-kind"`
+}
+
+// Read OpenAPI documents in JSON, given as files or directories (searched
+// recursively).
+func LoadOpenAPI(paths ...string) ([]*OpenAPIDump, error) {
+	var docs []*OpenAPIDump
+
+	err := readFiles(paths, []string{".json"}, func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var doc OpenAPIDump
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if doc.Swagger == "" && doc.OpenAPI == "" {
+			return fmt.Errorf("not an OpenAPI document")
+		}
+
+		docs = append(docs, &doc)
+		return nil
+	})
+
+	return docs, err
+}
+
+// A schema name in an OpenAPI document, e.g. io.k8s.api.apps.v1.Deployment:
+// the Go package path, reverse-domain style, and the type name.
+type openAPIName struct {
+	prefix, name string
+}
+
+func parseOpenAPIName(name string) openAPIName {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return openAPIName{name[:i], name[i+1:]}
+	}
+
+	return openAPIName{"", name}
+}
+
+// The Go package path of a prefix, e.g. io.k8s.api.apps.v1 is k8s.io/api/apps/v1.
+func (n openAPIName) pkgPath() string {
+	parts := strings.Split(n.prefix, ".")
+	if len(parts) < 2 {
+		return n.prefix
+	}
+
+	return strings.Join(append([]string{parts[1] + "." + parts[0]}, parts[2:]...), "/")
+}
+
+// What the paths of a document tell of a resource.
+type openAPIResource struct {
+	plural       string
+	namespaced   bool
+	actions      map[string]bool
+	subresources map[string]map[string]bool
+}
+
+// Generate a bundle from OpenAPI documents. Resources are the schemas of a
+// single kind with an ObjectMeta; their packages are exported as declared in
+// kure.toml (by path, or by group and version), or else in full. The schemas
+// of other packages are referred to as externs.
+func GenerateOpenAPIBundle(gctx *GeneratorContext, docs []*OpenAPIDump) (*spec.Bundle, error) {
+	conf := gctx.Config
+
+	schemas := make(map[string]*Schema)
+	for _, doc := range docs {
+		for name, s := range doc.schemas() {
+			schemas[name] = s
+		}
+	}
+	resources := openAPIResources(docs)
+
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// find the group-version of each package from its resources
+	packages := make(map[string]GroupVersionKind)
+	var prefixes []string
+	for _, name := range names {
+		gvk, ok := openAPIResourceGVK(schemas[name])
+		if !ok {
+			continue
+		}
+
+		n := parseOpenAPIName(name)
+		if _, seen := packages[n.prefix]; !seen {
+			packages[n.prefix] = gvk
+			prefixes = append(prefixes, n.prefix)
+		}
+	}
+
+	declared := len(conf.Exports) > 0
+	gens := make(map[string]*crdGroupVersion)
+	var gvs []*spec.APIGroupVersion
+
+	for _, prefix := range prefixes {
+		gvk := packages[prefix]
+		group := gvk.Group
+		if group == "" {
+			group = coreGroup
+		}
+
+		export := openAPIExport(conf, openAPIName{prefix: prefix}.pkgPath(), group, gvk.Version, declared)
+		if export == nil {
+			continue
+		}
+
+		gen := newCRDGroupVersion(conf, export)
+		gen.importer.ref = gen.openAPIReference(conf, prefix)
+		gens[prefix] = gen
+	}
+
+	for _, prefix := range prefixes {
+		gen := gens[prefix]
+		if gen == nil {
+			continue
+		}
+		im := gen.importer
+
+		var defs []string
+		for _, name := range names {
+			n := parseOpenAPIName(name)
+			if n.prefix == prefix && im.export.Includes(n.name) && !isListSchema(schemas[name]) {
+				im.reserve(n.name)
+				defs = append(defs, name)
+			}
+		}
+
+		for _, name := range defs {
+			n := parseOpenAPIName(name)
+			s := schemas[name]
+
+			var err error
+			if gvk, ok := openAPIResourceGVK(s); ok {
+				err = gen.openAPIResource(n.name, s, gvk, resources[gvk])
+			} else {
+				err = im.define(n.name, s)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		gen.gv.Definitions = im.defs
+		gen.gv.Dependencies = append(gen.gv.Dependencies, sortedDependencies(gen.deps)...)
+		gvs = append(gvs, gen.gv)
+	}
+
+	return assembleBundle(gctx, gvs)
+}
+
+// Find the export of a package, declaring one if none are configured.
+func openAPIExport(conf *config.Config, path, group, version string, declared bool) *config.Export {
+	for _, export := range conf.Exports {
+		if export.Path == path {
+			return export
+		}
+	}
+	for _, export := range conf.Exports {
+		if export.Path == "" && export.Group == group && export.Version == version {
+			// to resolve references from other packages
+			export.Path = path
+			return export
+		}
+	}
+
+	if declared {
+		return nil
+	}
+
+	return conf.AddExport(config.Export{Path: path, Group: group, Version: version})
+}
+
+// Resolve a $ref from the package prefix.
+func (gen *crdGroupVersion) openAPIReference(conf *config.Config, prefix string) func(string) (*spec.Type, error) {
+	return func(ref string) (*spec.Type, error) {
+		i := strings.LastIndexByte(ref, '/')
+		n := parseOpenAPIName(ref[i+1:])
+
+		if n.prefix == prefix {
+			return &spec.Type{
+				Variant: &spec.ReferenceType{Target: spec.ReferenceTarget{Name: n.name}},
+			}, nil
+		}
+
+		if t, err := mappedType(conf, gen.deps, n.pkgPath(), n.name); t != nil || err != nil {
+			return t, err
+		}
+
+		scope, dep, err := packageScope(conf, n.pkgPath())
+		if err != nil {
+			return nil, err
+		}
+		if dep != nil {
+			gen.deps[dep.Name] = dep
+		}
+
+		return &spec.Type{
+			Variant: &spec.ReferenceType{Target: spec.ReferenceTarget{Scope: scope, Name: n.name}},
+		}, nil
+	}
+}
+
+func (gen *crdGroupVersion) openAPIResource(kind string, s *Schema, gvk GroupVersionKind, res *openAPIResource) error {
+	im := gen.importer
+
+	i := len(im.defs)
+	im.defs = append(im.defs, spec.Definition{
+		DefinitionMeta: spec.DefinitionMeta{
+			Name:        kind,
+			Description: s.Description,
+			Validations: s.XValidations,
+		},
+	})
+
+	// the type identifiers are implied
+	body := *s
+	body.Properties = make(map[string]*Schema, len(s.Properties))
+	for name, ps := range s.Properties {
+		if name != "apiVersion" && name != "kind" {
+			body.Properties[name] = ps
+		}
+	}
+
+	props, err := im.properties(kind, &body)
+	if err != nil {
+		return err
+	}
+	requireSpec(props)
+
+	meta := spec.ResourceMeta{
+		Name:         resourceName(kind),
+		SingularName: strings.ToLower(kind),
+		Kind:         kind,
+		Scope:        spec.ScopeNamespace,
+	}
+
+	if res == nil {
+		if meta.Verbs, err = resourceVerbs(Comment{}, false); err != nil {
+			return err
+		}
+	} else {
+		meta.Name = res.plural
+		if !res.namespaced {
+			meta.Scope = spec.ScopeCluster
+		}
+
+		meta.Subresources, meta.Verbs = res.meta()
+	}
+
+	im.defs[i].Value = spec.Type{
+		Variant: &spec.ResourceType{Properties: props, Metadata: meta},
+	}
+
+	return nil
+}
+
+// The verbs of standardVerbs, by x-kubernetes-action.
+var openAPIActionVerbs = map[string][]string{
+	"get":              {"get"},
+	"list":             {"list"},
+	"watch":            {"watch"},
+	"watchlist":        {"watch"},
+	"post":             {"create"},
+	"put":              {"update"},
+	"patch":            {"patch", "apply"},
+	"delete":           {"delete"},
+	"deletecollection": {"deleteCollection"},
+}
+
+var openAPISubresourceVerbs = map[string]string{
+	"get":     "get",
+	"post":    "create",
+	"put":     "update",
+	"patch":   "patch",
+	"connect": "connect",
+}
+
+func (res *openAPIResource) meta() (sub spec.Subresources, verbs []string) {
+	supported := make(map[string]bool)
+	for action := range res.actions {
+		for _, verb := range openAPIActionVerbs[action] {
+			supported[verb] = true
+		}
+	}
+
+	status := res.subresources["status"]
+	sub.Status = status != nil
+	supported["updateStatus"] = status["put"]
+	supported["applyStatus"] = status["patch"]
+
+	if res.subresources["scale"] != nil {
+		sub.Scale = &spec.ScaleSubresource{}
+	}
+
+	names := make([]string, 0, len(res.subresources))
+	for name := range res.subresources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "status" || name == "scale" {
+			continue
+		}
+
+		var subVerbs []string
+		for action := range res.subresources[name] {
+			if verb := openAPISubresourceVerbs[action]; verb != "" {
+				subVerbs = append(subVerbs, verb)
+			}
+		}
+		sort.Strings(subVerbs)
+
+		for _, verb := range subVerbs {
+			sub.Additional = append(sub.Additional, spec.Subresource{Name: name, Verb: verb})
+		}
+	}
+
+	verbs = []string{}
+	for _, verb := range standardVerbs {
+		if supported[verb] {
+			verbs = append(verbs, verb)
+		}
+	}
+
+	return sub, verbs
+}
+
+// Find the resources served at the paths of the documents, by their kind.
+func openAPIResources(docs []*OpenAPIDump) map[GroupVersionKind]*openAPIResource {
+	type pathOperation struct {
+		apiVersion string
+		namespaced bool
+		segments   []string
+		openAPIOperation
+	}
+
+	var ops []pathOperation
+
+	for _, doc := range docs {
+		for path, item := range doc.Paths {
+			var apiVersion, rest string
+			if r, ok := strings.CutPrefix(path, "/apis/"); ok {
+				parts := strings.SplitN(r, "/", 3)
+				if len(parts) < 3 {
+					continue
+				}
+				apiVersion, rest = parts[0]+"/"+parts[1], parts[2]
+			} else if r, ok := strings.CutPrefix(path, "/api/"); ok {
+				if apiVersion, rest, ok = strings.Cut(r, "/"); !ok {
+					continue
+				}
+			} else {
+				continue
+			}
+
+			rest = strings.TrimPrefix(rest, "watch/")
+			rest, namespaced := strings.CutPrefix(rest, "namespaces/{namespace}/")
+
+			for method, raw := range item {
+				if method == "parameters" {
+					continue
+				}
+
+				var op openAPIOperation
+				if err := json.Unmarshal(raw, &op); err != nil || op.GVK == nil {
+					continue
+				}
+
+				ops = append(ops, pathOperation{apiVersion, namespaced, strings.Split(rest, "/"), op})
+			}
+		}
+	}
+
+	resources := make(map[GroupVersionKind]*openAPIResource)
+	byPlural := make(map[string]*openAPIResource)
+
+	for _, op := range ops {
+		if len(op.segments) > 2 {
+			continue
+		}
+
+		res := resources[*op.GVK]
+		if res == nil {
+			res = &openAPIResource{
+				plural:       op.segments[0],
+				actions:      make(map[string]bool),
+				subresources: make(map[string]map[string]bool),
+			}
+			resources[*op.GVK] = res
+			byPlural[op.apiVersion+"/"+res.plural] = res
+		}
+
+		res.namespaced = res.namespaced || op.namespaced
+		res.actions[op.Action] = true
+	}
+
+	// subresources may have other kinds (e.g. autoscaling/v1 Scale)
+	for _, op := range ops {
+		if len(op.segments) != 3 {
+			continue
+		}
+
+		res := byPlural[op.apiVersion+"/"+op.segments[0]]
+		if res == nil {
+			continue
+		}
+
+		name := op.segments[2]
+		if res.subresources[name] == nil {
+			res.subresources[name] = make(map[string]bool)
+		}
+		res.subresources[name][op.Action] = true
+	}
+
+	return resources
+}
+
+// The kind of a resource schema: one with a single kind and an ObjectMeta.
+func openAPIResourceGVK(s *Schema) (GroupVersionKind, bool) {
+	if len(s.XGroupVersionKind) != 1 {
+		return GroupVersionKind{}, false
+	}
+
+	return s.XGroupVersionKind[0], schemaRefersTo(s.Properties["metadata"], "ObjectMeta")
+}
+
+// Whether a schema is of a list (with a ListMeta), which are not exported.
+func isListSchema(s *Schema) bool {
+	return schemaRefersTo(s.Properties["metadata"], "ListMeta")
+}
+
+func schemaRefersTo(s *Schema, name string) bool {
+	if s == nil {
+		return false
+	}
+	if len(s.AllOf) == 1 {
+		s = s.AllOf[0]
+	}
+
+	return strings.HasSuffix(s.Ref, "."+name)
+}
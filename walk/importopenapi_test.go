@@ -0,0 +1,138 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+const metricsOpenAPI = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.29"},
+  "paths": {
+    "/apis/metrics.k8s.io/v1beta1/namespaces/{namespace}/pods": {
+      "get": {"x-kubernetes-action": "list", "x-kubernetes-group-version-kind": {"group": "metrics.k8s.io", "version": "v1beta1", "kind": "PodMetrics"}}
+    },
+    "/apis/metrics.k8s.io/v1beta1/namespaces/{namespace}/pods/{name}": {
+      "get": {"x-kubernetes-action": "get", "x-kubernetes-group-version-kind": {"group": "metrics.k8s.io", "version": "v1beta1", "kind": "PodMetrics"}}
+    }
+  },
+  "definitions": {
+    "io.k8s.metrics.pkg.apis.metrics.v1beta1.ContainerMetrics": {
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": {"type": "string"},
+        "usage": {"type": "object", "additionalProperties": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.api.resource.Quantity"}}
+      }
+    },
+    "io.k8s.metrics.pkg.apis.metrics.v1beta1.PodMetrics": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "containers": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.metrics.pkg.apis.metrics.v1beta1.ContainerMetrics"}}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "metrics.k8s.io", "kind": "PodMetrics", "version": "v1beta1"}]
+    },
+    "io.k8s.metrics.pkg.apis.metrics.v1beta1.PodMetricsList": {
+      "type": "object",
+      "properties": {
+        "items": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.metrics.pkg.apis.metrics.v1beta1.PodMetrics"}},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ListMeta"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "metrics.k8s.io", "kind": "PodMetricsList", "version": "v1beta1"}]
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {"type": "object", "properties": {"name": {"type": "string"}}}
+  }
+}`
+
+func TestParseOpenAPIName(t *testing.T) {
+	tests := []struct {
+		name, prefix, typeName, pkgPath string
+	}{
+		{"io.k8s.api.apps.v1.Deployment", "io.k8s.api.apps.v1", "Deployment", "k8s.io/api/apps/v1"},
+		{"com.example.widgets.v1.Widget", "com.example.widgets.v1", "Widget", "example.com/widgets/v1"},
+		{"Widget", "", "Widget", ""},
+	}
+
+	for _, tt := range tests {
+		n := parseOpenAPIName(tt.name)
+		if n.prefix != tt.prefix || n.name != tt.typeName {
+			t.Errorf("%s: parsed %+v", tt.name, n)
+		}
+		if got := n.pkgPath(); got != tt.pkgPath {
+			t.Errorf("%s: package path %q, want %q", tt.name, got, tt.pkgPath)
+		}
+	}
+}
+
+func TestGenerateOpenAPIBundle(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "metrics.json"), []byte(metricsOpenAPI), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := LoadOpenAPI(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config.Config{
+		Name:         "metrics",
+		Dependencies: []config.Dependency{{Name: config.KubernetesDependency, Version: "1.29"}},
+	}
+	bundle, err := GenerateOpenAPIBundle(NewGeneratorContext(conf, nil), docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Versions) != 1 {
+		t.Fatalf("generated %d group-versions", len(bundle.Versions))
+	}
+
+	gv := bundle.Versions[0]
+	if gv.Group.Name != "metrics.k8s.io" || gv.Version != "v1beta1" {
+		t.Errorf("group-version %s/%s", gv.Group.Name, gv.Version)
+	}
+	if got, want := definitionNames(gv), []string{"ContainerMetrics", "PodMetrics"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("definitions %v, want %v (without lists)", got, want)
+	}
+
+	res, ok := gv.Definitions[1].Value.Variant.(*spec.ResourceType)
+	if !ok {
+		t.Fatalf("PodMetrics is a %s", gv.Definitions[1].Value.Variant.Variant())
+	}
+	meta := res.Metadata
+	if meta.Name != "pods" || meta.Scope != spec.ScopeNamespace || !reflect.DeepEqual(meta.Verbs, []string{"get", "list"}) {
+		t.Errorf("metadata %+v", meta)
+	}
+
+	containers := definitionProperty(t, gv, "PodMetrics", "containers")
+	items := containers.Value.Variant.(*spec.ArrayType).Values
+	if ref, ok := items.Variant.(*spec.ReferenceType); !ok || ref.Target.Scope != nil || ref.Target.Name != "ContainerMetrics" {
+		t.Errorf("containers of %#v", items.Variant)
+	}
+
+	// Quantity follows its type mapping
+	usage := definitionProperty(t, gv, "ContainerMetrics", "usage")
+	values := usage.Value.Variant.(*spec.MapType).Values
+	if ref, ok := values.Variant.(*spec.ReferenceType); !ok || ref.Target.Name != "Quantity" || ref.Target.Scope.Package != "kubernetes" {
+		t.Errorf("usage of %#v", values.Variant)
+	}
+}
+
+func TestLoadOpenAPIInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.json")
+	if err := os.WriteFile(path, []byte(`{"kind": "List"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadOpenAPI(path); err == nil {
+		t.Error("expected an error for a document which is not OpenAPI")
+	}
+}
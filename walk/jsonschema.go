@@ -0,0 +1,89 @@
+package walk
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Build a standalone JSON Schema for every resource in the bundle, keyed by
+// the path of its file: <group>/<kind>_<version>.json. The definitions each
+// resource refers to are included under $defs.
+func JSONSchemas(bundle *spec.Bundle, deps map[string]*spec.Bundle) (map[string]*Schema, error) {
+	defs := newDefinitionIndex(bundle, deps)
+	schemas := make(map[string]*Schema)
+
+	for _, gv := range bundle.Versions {
+		for i := range gv.Definitions {
+			def := &gv.Definitions[i]
+			res, ok := def.Value.Variant.(*spec.ResourceType)
+			if !ok {
+				continue
+			}
+
+			converter := newSchemaConverter(defs, dialectJSONSchema)
+			s, err := converter.definition(&indexedDefinition{typeContext{gv: gv}, def})
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s %s: %w", gv.Group.Name, gv.Version, def.Name, err)
+			}
+
+			s.Defs = make(map[string]*Schema)
+			if err := converter.referenced(s.Defs); err != nil {
+				return nil, fmt.Errorf("%s/%s %s: %w", gv.Group.Name, gv.Version, def.Name, err)
+			}
+			if len(s.Defs) == 0 {
+				s.Defs = nil
+			}
+
+			// pin the type identifiers, so editors can match documents
+			gvk := resourceGVK(gv, res)
+			s.Properties["apiVersion"].Enum = []any{gvk.APIVersion()}
+			s.Properties["kind"].Enum = []any{gvk.Kind}
+			s.Schema = jsonSchemaDialect
+			s.Title = gvk.Kind
+
+			filename := fmt.Sprintf("%s_%s.json", strings.ToLower(gvk.Kind), gv.Version)
+			schemas[path.Join(gv.Group.Name, filename)] = s
+		}
+	}
+
+	return schemas, nil
+}
+
+// Write a JSON Schema file for every resource in the bundle.
+func WriteJSONSchemas(bundle *spec.Bundle, deps map[string]*spec.Bundle, out string) error {
+	files, err := JSONSchemaFiles(bundle, deps, out)
+	if err != nil {
+		return err
+	}
+
+	return files.Write()
+}
+
+// The JSON Schema files of the resources in a bundle, in the directory out.
+func JSONSchemaFiles(bundle *spec.Bundle, deps map[string]*spec.Bundle, out string) (FileSet, error) {
+	schemas, err := JSONSchemas(bundle, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	filenames := make([]string, 0, len(schemas))
+	for filename := range schemas {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var files FileSet
+	for _, filename := range filenames {
+		if err := files.add(path.Join(out, filename), schemas[filename]); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
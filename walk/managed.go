@@ -0,0 +1,285 @@
+package walk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The manifest of a managed output directory, listing the files written to
+// it (relative to the directory).
+const manifestName = ".kure-manifest.json"
+
+type manifest struct {
+	Files []string `json:"files"`
+}
+
+// Write the files into the managed directory dir. Files listed in its
+// manifest which are no longer generated are removed, while files which were
+// never generated are kept. The new directory, manifest included, is
+// assembled in a temporary directory beside dir and renamed into place, so an
+// interrupted write leaves the previous output intact.
+func (s FileSet) WriteManaged(dir string) error {
+	files, err := s.relative(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := checkManagedDir(dir); err != nil {
+		return err
+	}
+
+	owned, err := readManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	// keep the mode of the directory replaced (MkdirTemp creates it 0700)
+	mode := fs.FileMode(0755)
+	if info, err := os.Stat(dir); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		return err
+	}
+
+	if err := keepUnowned(dir, tmp, owned, files); err != nil {
+		return err
+	}
+
+	generated := make([]string, 0, len(files))
+	for rel, f := range files {
+		target := filepath.Join(tmp, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, f.data, 0644); err != nil {
+			return err
+		}
+
+		generated = append(generated, filepath.ToSlash(rel))
+	}
+	sort.Strings(generated)
+
+	if err := writeManifest(tmp, generated); err != nil {
+		return err
+	}
+
+	return replaceDir(dir, tmp)
+}
+
+// Compare the files with those on disk like Check, also reporting the files
+// of the managed directory dir which would be removed.
+func (s FileSet) CheckManaged(dir string, w io.Writer) (bool, error) {
+	current, err := s.Check(w)
+	if err != nil {
+		return false, err
+	}
+
+	files, err := s.relative(dir)
+	if err != nil {
+		return false, err
+	}
+
+	owned, err := readManifest(dir)
+	if err != nil {
+		return false, err
+	}
+
+	for _, rel := range owned {
+		if _, ok := files[filepath.FromSlash(rel)]; ok {
+			continue
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		old, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+
+		current = false
+		if _, err := io.WriteString(w, unifiedDiff(path, "/dev/null", old, nil)); err != nil {
+			return false, err
+		}
+	}
+
+	return current, nil
+}
+
+// Index the files by their path relative to dir, which must contain them.
+func (s FileSet) relative(dir string) (map[string]*file, error) {
+	files := make(map[string]*file, len(s))
+
+	for _, f := range s {
+		rel, err := filepath.Rel(dir, f.path)
+		if err != nil {
+			return nil, err
+		}
+		if rel == manifestName || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is not a file within %s", f.path, dir)
+		}
+
+		files[rel] = f
+	}
+
+	return files, nil
+}
+
+// Refuse to replace a directory which holds the working directory (e.g. the
+// project itself).
+func checkManagedDir(dir string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if rel, err := filepath.Rel(dir, wd); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("managed output directory %s contains the working directory", dir)
+	}
+
+	return nil
+}
+
+// Read the files listed in the manifest of dir, if any.
+func readManifest(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", manifestName, err)
+	}
+
+	return m.Files, nil
+}
+
+func writeManifest(dir string, files []string) error {
+	data, err := json.MarshalIndent(&manifest{Files: files}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, manifestName), data, 0644)
+}
+
+// Carry the files of dir which are neither owned nor generated over to tmp,
+// along with its empty directories. Directories left empty by the removal of
+// owned files are not carried over.
+func keepUnowned(dir, tmp string, owned []string, files map[string]*file) error {
+	skip := make(map[string]bool, len(owned)+len(files)+1)
+	for _, rel := range owned {
+		skip[filepath.FromSlash(rel)] = true
+	}
+	for rel := range files {
+		skip[rel] = true
+	}
+	skip[manifestName] = true
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(tmp, rel)
+
+		switch {
+		case d.IsDir():
+			entries, err := os.ReadDir(path)
+			if err != nil || len(entries) > 0 {
+				return err
+			}
+			return os.MkdirAll(target, 0755)
+		case skip[rel]:
+			return nil
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		}
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func copyFile(from, to string) error {
+	if err := os.Link(from, to); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(to, data, info.Mode().Perm())
+}
+
+// Move tmp into place of dir, removing the old directory. The old directory
+// is moved aside first (a directory cannot be renamed over another), and
+// restored if tmp cannot take its place.
+func replaceDir(dir, tmp string) error {
+	old := tmp + ".old"
+
+	if err := os.Rename(dir, old); errors.Is(err, fs.ErrNotExist) {
+		return os.Rename(tmp, dir)
+	} else if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, dir); err != nil {
+		// restore the old directory
+		if rerr := os.Rename(old, dir); rerr != nil {
+			return fmt.Errorf("%w (previous output left in %s)", err, old)
+		}
+		return err
+	}
+
+	return os.RemoveAll(old)
+}
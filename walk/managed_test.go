@@ -0,0 +1,154 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testFileSet(dir string, names ...string) FileSet {
+	var files FileSet
+	for _, name := range names {
+		files.addData(filepath.Join(dir, name), []byte(name+"\n"))
+	}
+
+	return files
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestWriteManaged(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+
+	if err := testFileSet(dir, "index.json", "apps/v1.json", "apps/v2.json").WriteManaged(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	owned, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"apps/v1.json", "apps/v2.json", "index.json"}; !reflect.DeepEqual(owned, want) {
+		t.Errorf("manifest %v, want %v", owned, want)
+	}
+
+	// a file which was never generated, and a custom mode of the directory
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testFileSet(dir, "index.json", "core/v1.json").WriteManaged(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if owned, err = readManifest(dir); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"core/v1.json", "index.json"}; !reflect.DeepEqual(owned, want) {
+		t.Errorf("manifest %v, want %v", owned, want)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "apps")); !os.IsNotExist(err) {
+		t.Errorf("stale directory apps not removed: %v", err)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "README.md")); got != "notes\n" {
+		t.Errorf("unowned README.md changed to %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "core", "v1.json")); got != "core/v1.json\n" {
+		t.Errorf("core/v1.json is %q", got)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("directory mode %v, want 0700", info.Mode().Perm())
+	}
+}
+
+func TestWriteManagedInterrupted(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "out")
+
+	if err := testFileSet(dir, "index.json", "apps/v1.json").WriteManaged(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// apps cannot be both a file and the directory of apps/v1.json
+	if err := testFileSet(dir, "index.json", "apps", "apps/v1.json").WriteManaged(dir); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+
+	owned, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"apps/v1.json", "index.json"}; !reflect.DeepEqual(owned, want) {
+		t.Errorf("manifest %v, want %v", owned, want)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "apps", "v1.json")); got != "apps/v1.json\n" {
+		t.Errorf("apps/v1.json changed to %q", got)
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary directories left behind: %v", entries)
+	}
+}
+
+func TestWriteManagedOutside(t *testing.T) {
+	dir := t.TempDir()
+	files := testFileSet(dir, "index.json")
+	files.addData(filepath.Join(filepath.Dir(dir), "index.json"), nil)
+
+	if err := files.WriteManaged(dir); err == nil {
+		t.Error("expected an error for a file outside the directory")
+	}
+}
+
+func TestCheckManaged(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := testFileSet(dir, "index.json", "apps/v1.json").WriteManaged(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	current, err := testFileSet(dir, "index.json", "apps/v1.json").CheckManaged(dir, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current || out.Len() != 0 {
+		t.Errorf("current = %v, output %q", current, out.String())
+	}
+
+	out.Reset()
+	current, err = testFileSet(dir, "index.json").CheckManaged(dir, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := filepath.Join(dir, "apps", "v1.json")
+	want := "--- " + stale + "\n+++ /dev/null\n@@ -1 +0,0 @@\n-apps/v1.json\n"
+	if current || out.String() != want {
+		t.Errorf("current = %v, output:\n%s\nwant:\n%s", current, out.String(), want)
+	}
+}
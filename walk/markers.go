@@ -1,6 +1,7 @@
 package walk
 
 import (
+	"fmt"
 	"go/ast"
 	"regexp"
 	"strconv"
@@ -80,6 +81,47 @@ func (c *Comment) Marker(name string) string {
 	return ""
 }
 
+// Whether the marker is present, with or without a value.
+func (c *Comment) HasMarker(name string) bool {
+	prefix := name + "="
+
+	for _, m := range c.Markers {
+		if m == name || strings.HasPrefix(m, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the values of every marker with the given name, in order.
+func (c *Comment) MarkerValues(name string) (values []string) {
+	prefix := name + "="
+
+	for _, m := range c.Markers {
+		if m == name {
+			values = append(values, "true")
+		} else if strings.HasPrefix(m, prefix) {
+			values = append(values, strings.TrimPrefix(m, prefix))
+		}
+	}
+
+	return
+}
+
+// Return the arguments of every marker of the form name:args, in order.
+func (c *Comment) MarkerArgs(name string) (args []string) {
+	prefix := name + ":"
+
+	for _, m := range c.Markers {
+		if strings.HasPrefix(m, prefix) {
+			args = append(args, strings.TrimPrefix(m, prefix))
+		}
+	}
+
+	return
+}
+
 func (c *Comment) Deprecated() bool {
 	return deprecation.MatchString(c.Text)
 }
@@ -116,26 +158,33 @@ func (c packageComments) get(filename string, lastLine int) *ast.CommentGroup {
 	return nil
 }
 
-// Scan a ;-separated list of quoted strings and bare strings.
+// Scan a ;-separated list of quoted strings and bare strings, or a {a,b}
+// list, which may also be ,-separated.
 func scanEnumValidation(spec string) (values []string, err error) {
+	if spec == "" {
+		return nil, nil
+	}
+
 	i := 0
 	l := len(spec)
 
 	brace := false
+	separators := `";`
 	if spec[0] == '{' && spec[l-1] == '}' {
 		brace = true
+		separators = `";,`
 		i++
 		l--
 	}
 
 	for i < l {
-		n := strings.IndexAny(spec[i:], `";`)
+		n := strings.IndexAny(spec[i:l], separators)
 		if n < 0 {
 			break
 		}
 
 		switch spec[i+n] {
-		case ';':
+		case ';', ',':
 			values = append(values, spec[i:i+n])
 			i += n + 1
 
@@ -164,3 +213,94 @@ func scanEnumValidation(spec string) (values []string, err error) {
 
 	return
 }
+
+// Scan a ,-separated list of key=value marker arguments, where values may be
+// bare, "quoted" or `raw` strings, or {a,b} lists (kept whole, braces
+// included).
+func scanMarkerArgs(spec string) (map[string]string, error) {
+	args := make(map[string]string)
+	i := 0
+
+	for i < len(spec) {
+		eq := strings.IndexByte(spec[i:], '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("expected key=value at %q", spec[i:])
+		}
+
+		key := strings.TrimSpace(spec[i : i+eq])
+		i += eq + 1
+
+		var value string
+		switch {
+		case i < len(spec) && spec[i] == '"':
+			quoted, err := strconv.QuotedPrefix(spec[i:])
+			if err != nil {
+				return nil, fmt.Errorf("argument %s: %w", key, err)
+			}
+
+			value, err = strconv.Unquote(quoted)
+			if err != nil {
+				return nil, fmt.Errorf("argument %s: %w", key, err)
+			}
+
+			i += len(quoted)
+
+		case i < len(spec) && spec[i] == '`':
+			end := strings.IndexByte(spec[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("argument %s: unterminated raw string", key)
+			}
+
+			value = spec[i+1 : i+1+end]
+			i += end + 2
+
+		case i < len(spec) && spec[i] == '{':
+			end, err := closingBrace(spec[i:])
+			if err != nil {
+				return nil, fmt.Errorf("argument %s: %w", key, err)
+			}
+
+			value = spec[i : i+end+1]
+			i += end + 1
+
+		default:
+			end := strings.IndexByte(spec[i:], ',')
+			if end < 0 {
+				end = len(spec) - i
+			}
+
+			value = strings.TrimSpace(spec[i : i+end])
+			i += end
+		}
+
+		args[key] = value
+
+		if i < len(spec) {
+			if spec[i] != ',' {
+				return nil, fmt.Errorf("expected , after argument %s", key)
+			}
+			i++
+		}
+	}
+
+	return args, nil
+}
+
+// Find the index of the } closing the list at the start of spec, skipping over
+// quoted strings.
+func closingBrace(spec string) (int, error) {
+	for i := 1; i < len(spec); i++ {
+		switch spec[i] {
+		case '}':
+			return i, nil
+		case '"':
+			quoted, err := strconv.QuotedPrefix(spec[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += len(quoted) - 1
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated list")
+}
@@ -0,0 +1,73 @@
+package walk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanMarkerArgs(t *testing.T) {
+	tests := []struct {
+		spec string
+		want map[string]string
+	}{
+		{``, map[string]string{}},
+		{`rule=self.a > 0`, map[string]string{"rule": "self.a > 0"}},
+		{`rule="self.a, self.b",message=bad`, map[string]string{"rule": "self.a, self.b", "message": "bad"}},
+		{"rule=`self.s == \"x\"`, reason=FieldValueInvalid", map[string]string{"rule": `self.s == "x"`, "reason": "FieldValueInvalid"}},
+		{`message="a \"quoted\" word", fieldPath=.spec`, map[string]string{"message": `a "quoted" word`, "fieldPath": ".spec"}},
+		{`key=`, map[string]string{"key": ""}},
+		{`shortName={a,b},scope=Cluster`, map[string]string{"shortName": "{a,b}", "scope": "Cluster"}},
+		{`categories={"a,}",b}`, map[string]string{"categories": `{"a,}",b}`}},
+	}
+
+	for _, tt := range tests {
+		got, err := scanMarkerArgs(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestScanMarkerArgsInvalid(t *testing.T) {
+	for _, spec := range []string{
+		`rule`,
+		`rule="unterminated`,
+		"rule=`unterminated",
+		`rule="a"b`,
+		`shortName={a,b`,
+	} {
+		if args, err := scanMarkerArgs(spec); err == nil {
+			t.Errorf("%s: expected an error, got %q", spec, args)
+		}
+	}
+}
+
+func TestScanEnumValidation(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{``, nil},
+		{`a`, []string{"a"}},
+		{`a;b;c`, []string{"a", "b", "c"}},
+		{`"a;b";c`, []string{"a;b", "c"}},
+		{`{"a","b"}`, []string{"a", "b"}},
+		{`{a,b}`, []string{"a", "b"}},
+		{`{a;"b,c"}`, []string{"a", "b,c"}},
+	}
+
+	for _, tt := range tests {
+		got, err := scanEnumValidation(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
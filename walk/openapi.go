@@ -0,0 +1,78 @@
+package walk
+
+import (
+	"path"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+type OpenAPI struct {
+	OpenAPI    string            `json:"openapi"`
+	Info       OpenAPIInfo       `json:"info"`
+	Paths      map[string]any    `json:"paths"`
+	Components OpenAPIComponents `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Build an OpenAPI v3 document with a component schema for every definition
+// in the bundle, and for the definitions of dependencies they refer to.
+func OpenAPIDocument(bundle *spec.Bundle, deps map[string]*spec.Bundle) (*OpenAPI, error) {
+	defs := newDefinitionIndex(bundle, deps)
+	converter := newSchemaConverter(defs, dialectOpenAPI)
+
+	for _, gv := range bundle.Versions {
+		for _, def := range gv.Definitions {
+			converter.name(defKey{"", gv.Group.String(), gv.Version, def.Name})
+		}
+	}
+
+	schemas := make(map[string]*Schema)
+	if err := converter.referenced(schemas); err != nil {
+		return nil, err
+	}
+
+	version := bundle.API.Version
+	if version == "" {
+		version = "0.0.0"
+	}
+
+	return &OpenAPI{
+		OpenAPI:    "3.0.3",
+		Info:       OpenAPIInfo{Title: bundle.API.Name, Version: version},
+		Paths:      map[string]any{},
+		Components: OpenAPIComponents{Schemas: schemas},
+	}, nil
+}
+
+// Write the bundle as an openapi.json document.
+func WriteOpenAPI(bundle *spec.Bundle, deps map[string]*spec.Bundle, out string) error {
+	files, err := OpenAPIFiles(bundle, deps, out)
+	if err != nil {
+		return err
+	}
+
+	return files.Write()
+}
+
+// The openapi.json document of a bundle, in the directory out.
+func OpenAPIFiles(bundle *spec.Bundle, deps map[string]*spec.Bundle, out string) (FileSet, error) {
+	doc, err := OpenAPIDocument(bundle, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	var files FileSet
+	if err := files.add(path.Join(out, "openapi.json"), doc); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
@@ -1,8 +1,12 @@
 package walk
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path"
 
@@ -10,19 +14,34 @@ import (
 )
 
 func WriteBundle(bundle *spec.Bundle, out string) error {
-	var files fileset
+	files, err := BundleFiles(bundle, out)
+	if err != nil {
+		return err
+	}
+
+	return files.Write()
+}
+
+// The files of a bundle, in the directory out.
+func BundleFiles(bundle *spec.Bundle, out string) (FileSet, error) {
+	var files FileSet
 
 	if err := files.add(path.Join(out, "index.json"), &bundle.API); err != nil {
-		return err
+		return nil, err
 	}
 
+	bases := make(map[string]string, len(bundle.Groups))
 	for _, group := range bundle.Groups {
 		base := out
 		if group.Module != nil {
 			base = path.Join(base, *group.Module)
 		}
+		if other, ok := bases[base]; ok {
+			return nil, fmt.Errorf("groups %s and %s would both be written to %s (declare a module for each)", other, group.Name, base)
+		}
+		bases[base] = group.Name
 		if err := files.add(path.Join(base, "group.json"), group); err != nil {
-			return err
+			return nil, err
 		}
 
 		for _, version := range bundle.Versions {
@@ -32,17 +51,18 @@ func WriteBundle(bundle *spec.Bundle, out string) error {
 
 			filename := fmt.Sprintf("%s.json", version.Version)
 			if err := files.add(path.Join(base, filename), version); err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	return files.write()
+	return files, nil
 }
 
-type fileset []*file
+// Generated files, held in memory until written.
+type FileSet []*file
 
-func (s *fileset) add(path string, contents any) error {
+func (s *FileSet) add(path string, contents any) error {
 	file, err := newFile(path, contents)
 	if file == nil {
 		return err
@@ -52,7 +72,11 @@ func (s *fileset) add(path string, contents any) error {
 	return nil
 }
 
-func (s fileset) write() error {
+func (s *FileSet) addData(path string, data []byte) {
+	*s = append(*s, &file{path: path, data: data})
+}
+
+func (s FileSet) Write() error {
 	for _, f := range s {
 		if err := os.MkdirAll(path.Dir(f.path), 0755); err != nil {
 			return err
@@ -66,6 +90,31 @@ func (s fileset) write() error {
 	return nil
 }
 
+// Compare the files with those on disk, printing a unified diff of each
+// difference to w. Reports whether every file is up to date.
+func (s FileSet) Check(w io.Writer) (bool, error) {
+	current := true
+
+	for _, f := range s {
+		oldPath := f.path
+		old, err := os.ReadFile(f.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			oldPath = "/dev/null"
+		} else if err != nil {
+			return false, err
+		} else if bytes.Equal(old, f.data) {
+			continue
+		}
+
+		current = false
+		if _, err := io.WriteString(w, unifiedDiff(oldPath, f.path, old, f.data)); err != nil {
+			return false, err
+		}
+	}
+
+	return current, nil
+}
+
 type file struct {
 	path string
 	data []byte
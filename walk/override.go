@@ -0,0 +1,126 @@
+package walk
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// Apply the overrides and renames of each export to its group-version.
+func applyOverrides(conf *config.Config, gvs []*spec.APIGroupVersion) error {
+	var errs []error
+
+	for _, gv := range gvs {
+		export := exportFor(conf, gv)
+		if export == nil {
+			continue
+		}
+
+		for i := range export.Overrides {
+			if err := applyOverride(gv, &export.Overrides[i]); err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s: %w", gv.Group.Name, gv.Version, err))
+			}
+		}
+
+		if len(export.Renames) == 0 {
+			continue
+		}
+
+		names, err := renameDefinitions(gv, export.Renames)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", gv.Group.Name, gv.Version, err))
+			continue
+		}
+
+		errs = append(errs, updateReferences(gvs, merge{from: gv, to: gv, names: names}))
+	}
+
+	return errors.Join(errs...)
+}
+
+func applyOverride(gv *spec.APIGroupVersion, o *config.Override) error {
+	i := slices.IndexFunc(gv.Definitions, func(def spec.Definition) bool { return def.Name == o.Definition })
+	if i < 0 {
+		return fmt.Errorf("cannot override %s: not defined", o.Definition)
+	}
+	def := &gv.Definitions[i]
+
+	if o.Property == "" {
+		if o.Name != "" || o.Hide {
+			return fmt.Errorf("cannot rename or hide definition %s (use rename or exclude)", o.Definition)
+		}
+
+		if o.Description != nil {
+			def.Description = *o.Description
+		}
+		return nil
+	}
+
+	var props *[]spec.Property
+	switch v := def.Value.Variant.(type) {
+	case *spec.ObjectType:
+		props = &v.Properties
+	case *spec.ResourceType:
+		props = &v.Properties
+	default:
+		return fmt.Errorf("cannot override %s.%s: not an object", o.Definition, o.Property)
+	}
+
+	j := slices.IndexFunc(*props, func(prop spec.Property) bool { return prop.Name == o.Property })
+	if j < 0 {
+		return fmt.Errorf("cannot override %s.%s: no such property", o.Definition, o.Property)
+	}
+
+	if o.Hide {
+		if _, ok := def.Value.Variant.(*spec.ResourceType); ok && (o.Property == "spec" || o.Property == "status") {
+			return fmt.Errorf("cannot hide %s.%s: resources keep their spec and status", o.Definition, o.Property)
+		}
+
+		*props = slices.Delete(*props, j, j+1)
+		return nil
+	}
+
+	prop := &(*props)[j]
+	if o.Name != "" {
+		prop.Name = o.Name
+	}
+	if o.Description != nil {
+		prop.Description = *o.Description
+	}
+
+	return nil
+}
+
+// Rename the definitions of a group-version, returning the new name of each.
+func renameDefinitions(gv *spec.APIGroupVersion, renames []config.Rename) (map[string]string, error) {
+	names := make(map[string]string, len(gv.Definitions))
+	for _, def := range gv.Definitions {
+		names[def.Name] = def.Name
+	}
+
+	var errs []error
+	for _, r := range renames {
+		if _, ok := names[r.From]; !ok {
+			errs = append(errs, fmt.Errorf("cannot rename %s: not defined", r.From))
+			continue
+		}
+
+		names[r.From] = r.To
+	}
+
+	taken := make(map[string]bool, len(gv.Definitions))
+	for i := range gv.Definitions {
+		def := &gv.Definitions[i]
+		def.Name = names[def.Name]
+
+		if taken[def.Name] {
+			errs = append(errs, fmt.Errorf("duplicate definition %s", def.Name))
+		}
+		taken[def.Name] = true
+	}
+
+	return names, errors.Join(errs...)
+}
@@ -0,0 +1,132 @@
+package walk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	scope := &spec.ReferenceScope{Group: spec.APIGroupIdentifier{Name: "widgets.example.com"}, Version: "v1"}
+	description := "The specification of a widget."
+
+	widgets := testGroupVersion("widgets.example.com",
+		referringDefinition("Widget", spec.ReferenceTarget{Name: "Spec"}, spec.ReferenceTarget{Name: "Status"}),
+		referringDefinition("Spec"),
+		referringDefinition("Status"))
+	gadgets := testGroupVersion("gadgets.example.com",
+		referringDefinition("Gadget", spec.ReferenceTarget{Scope: scope, Name: "Spec"}))
+
+	conf := &config.Config{Exports: []*config.Export{
+		{
+			Group: "widgets.example.com", Version: "v1",
+			Renames: []config.Rename{{From: "Spec", To: "WidgetSpec"}},
+			Overrides: []config.Override{
+				{Definition: "Spec", Description: &description},
+				{Definition: "Widget", Property: "spec", Name: "specification"},
+				{Definition: "Widget", Property: "status", Hide: true},
+			},
+		},
+		{Group: "gadgets.example.com", Version: "v1"},
+	}}
+
+	if err := applyOverrides(conf, []*spec.APIGroupVersion{widgets, gadgets}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := definitionNames(widgets), []string{"Widget", "WidgetSpec", "Status"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("definitions %v, want %v", got, want)
+	}
+	if got := widgets.Definitions[1].Description; got != description {
+		t.Errorf("description %q, want %q", got, description)
+	}
+
+	if got, want := referenceTargets(widgets), map[string]string{"Widget.specification": "WidgetSpec"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("references %v, want %v", got, want)
+	}
+	if got, want := referenceTargets(gadgets), map[string]string{"Gadget.spec": "widgets.example.com/v1.WidgetSpec"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("references %v, want %v", got, want)
+	}
+}
+
+func TestApplyOverrideErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		override config.Override
+	}{
+		{"undefined", config.Override{Definition: "Missing"}},
+		{"rename definition", config.Override{Definition: "Widget", Name: "Gadget"}},
+		{"hide definition", config.Override{Definition: "Widget", Hide: true}},
+		{"no such property", config.Override{Definition: "Widget", Property: "missing", Hide: true}},
+		{"not an object", config.Override{Definition: "Mode", Property: "value", Hide: true}},
+		{"hide resource spec", config.Override{Definition: "Gizmo", Property: "spec", Hide: true}},
+		{"hide resource status", config.Override{Definition: "Gizmo", Property: "status", Hide: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gv := testGroupVersion("widgets.example.com",
+				referringDefinition("Widget", spec.ReferenceTarget{Name: "Mode"}),
+				spec.Definition{
+					DefinitionMeta: spec.DefinitionMeta{Name: "Mode"},
+					Value:          spec.Type{Variant: &spec.StringType{}},
+				},
+				spec.Definition{
+					DefinitionMeta: spec.DefinitionMeta{Name: "Gizmo"},
+					Value: spec.Type{Variant: &spec.ResourceType{Properties: []spec.Property{
+						{PropertyMeta: spec.PropertyMeta{DefinitionMeta: spec.DefinitionMeta{Name: "spec"}}},
+						{PropertyMeta: spec.PropertyMeta{DefinitionMeta: spec.DefinitionMeta{Name: "status"}}},
+					}}},
+				})
+
+			if err := applyOverride(gv, &tt.override); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestRenameDefinitionsErrors(t *testing.T) {
+	gv := testGroupVersion("widgets.example.com", referringDefinition("Widget"), referringDefinition("Gadget"))
+
+	if _, err := renameDefinitions(gv, []config.Rename{{From: "Gadget", To: "Widget"}}); err == nil {
+		t.Error("expected a duplicate definition error")
+	}
+	if _, err := renameDefinitions(gv, []config.Rename{{From: "Missing", To: "Found"}}); err == nil {
+		t.Error("expected an error renaming an undefined definition")
+	}
+}
+
+func TestRenameBeforeMerge(t *testing.T) {
+	widgets := testGroupVersion("widgets.example.com", referringDefinition("Widget"))
+	internal := testGroupVersion("internal.example.com",
+		referringDefinition("Spec"),
+		referringDefinition("Status"))
+
+	conf := &config.Config{Exports: []*config.Export{
+		{Group: "widgets.example.com", Version: "v1"},
+		{
+			Group: "internal.example.com", Version: "v1",
+			Renames: []config.Rename{{From: "Spec", To: "GadgetSpec"}},
+			Merge: &config.Merge{
+				Group:   "widgets.example.com",
+				Include: []string{"GadgetSpec"},
+				Rename:  map[string]string{"GadgetSpec": "WidgetGadgetSpec"},
+			},
+		},
+	}}
+
+	gvs := []*spec.APIGroupVersion{widgets, internal}
+	if err := applyOverrides(conf, gvs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyMerges(conf, gvs); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := definitionNames(widgets), []string{"Widget", "WidgetGadgetSpec"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("definitions %v, want %v", got, want)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"sort"
 
 	"github.com/kure-sh/ingest-go/spec"
 	"golang.org/x/tools/go/packages"
@@ -19,6 +20,8 @@ type Package struct {
 	Group *PackageGroup
 	Local bool
 
+	// The package doc comment, with markers from every file's header
+	comment  Comment
 	docTypes map[string]*doc.Type
 }
 
@@ -80,6 +83,17 @@ func (p *Package) initialize() error {
 		p.docTypes[dt.Name] = dt
 	}
 
+	// Package markers (e.g., +groupName) are often separated from the package
+	// clause by a blank line, so they are not part of the doc comment.
+	p.comment = ReadComment(p.doc.Doc)
+	for _, file := range p.pkg.Syntax {
+		for _, group := range file.Comments {
+			if group.End() < file.Package && group != file.Doc {
+				p.comment.AddMarkers(group)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -138,3 +152,14 @@ type Declarations struct {
 	Types     []*types.TypeName
 	Constants []*types.Const
 }
+
+// Reorder the declarations as they appear in the package's files, rather than
+// by name.
+func (d *Declarations) SortBySource() {
+	sort.SliceStable(d.Types, func(i, j int) bool {
+		return d.Types[i].Pos() < d.Types[j].Pos()
+	})
+	sort.SliceStable(d.Constants, func(i, j int) bool {
+		return d.Constants[i].Pos() < d.Constants[j].Pos()
+	})
+}
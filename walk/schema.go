@@ -0,0 +1,581 @@
+package walk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// A JSON schema, as the OpenAPI v3 schema object used by Kubernetes.
+type Schema struct {
+	Schema      string          `json:"$schema,omitempty"`
+	Ref         string          `json:"$ref,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Format      string          `json:"format,omitempty"`
+	Nullable    bool            `json:"nullable,omitempty"`
+	Enum        []any           `json:"enum,omitempty"`
+	Default     json.RawMessage `json:"default,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum any      `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum any      `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
+	MinLength        *int64   `json:"minLength,omitempty"`
+	MaxLength        *int64   `json:"maxLength,omitempty"`
+	Pattern          string   `json:"pattern,omitempty"`
+	MinItems         *int64   `json:"minItems,omitempty"`
+	MaxItems         *int64   `json:"maxItems,omitempty"`
+	UniqueItems      bool     `json:"uniqueItems,omitempty"`
+	MinProperties    *int64   `json:"minProperties,omitempty"`
+	MaxProperties    *int64   `json:"maxProperties,omitempty"`
+
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+
+	XIntOrString           bool                  `json:"x-kubernetes-int-or-string,omitempty"`
+	XPreserveUnknownFields bool                  `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+	XListType              string                `json:"x-kubernetes-list-type,omitempty"`
+	XListMapKeys           []string              `json:"x-kubernetes-list-map-keys,omitempty"`
+	XMapType               string                `json:"x-kubernetes-map-type,omitempty"`
+	XValidations           []spec.ValidationRule `json:"x-kubernetes-validations,omitempty"`
+	XGroupVersionKind      []GroupVersionKind    `json:"x-kubernetes-group-version-kind,omitempty"`
+	XEmbeddedResource      bool                  `json:"x-kubernetes-embedded-resource,omitempty"`
+	XPatchStrategy         string                `json:"x-kubernetes-patch-strategy,omitempty"`
+	XPatchMergeKey         string                `json:"x-kubernetes-patch-merge-key,omitempty"`
+
+	Defs map[string]*Schema `json:"$defs,omitempty"`
+
+	// A false boolean schema, which matches nothing
+	never bool
+}
+
+// Read a schema, which may be a boolean: true allows any value, and false
+// none (e.g. for additionalProperties).
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{never: true}
+		return nil
+	}
+
+	type schema Schema
+	return json.Unmarshal(data, (*schema)(s))
+}
+
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+func (gvk GroupVersionKind) APIVersion() string {
+	if gvk.Group == "" {
+		return gvk.Version
+	}
+
+	return gvk.Group + "/" + gvk.Version
+}
+
+func resourceGVK(gv *spec.APIGroupVersion, res *spec.ResourceType) GroupVersionKind {
+	group := gv.Group.Name
+	if group == coreGroup {
+		group = ""
+	}
+
+	return GroupVersionKind{Group: group, Version: gv.Version, Kind: res.Metadata.Kind}
+}
+
+// Schemas of builtin Kubernetes types, used when the kubernetes bundle is not
+// available as a dependency.
+var kubernetesSchemas = map[string]func() *Schema{
+	"kubernetes:meta/meta/v1.Quantity": func() *Schema {
+		return &Schema{
+			AnyOf:        []*Schema{{Type: "integer"}, {Type: "string"}},
+			Pattern:      `^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`,
+			XIntOrString: true,
+		}
+	},
+	"kubernetes:meta/meta/v1.Time":       func() *Schema { return &Schema{Type: "string", Format: "date-time"} },
+	"kubernetes:meta/meta/v1.MicroTime":  func() *Schema { return &Schema{Type: "string", Format: "date-time"} },
+	"kubernetes:meta/meta/v1.Duration":   func() *Schema { return &Schema{Type: "string"} },
+	"kubernetes:meta/meta/v1.ObjectMeta": func() *Schema { return &Schema{Type: "object"} },
+}
+
+type defKey struct {
+	pkg, group, version, name string
+}
+
+// The location of a type, which scopes its references.
+type typeContext struct {
+	pkg string
+	gv  *spec.APIGroupVersion
+}
+
+type indexedDefinition struct {
+	typeContext
+	def *spec.Definition
+}
+
+// Definitions of a bundle and its dependencies, indexed by package, group,
+// version and name.
+type definitionIndex map[defKey]indexedDefinition
+
+func newDefinitionIndex(bundle *spec.Bundle, deps map[string]*spec.Bundle) definitionIndex {
+	index := make(definitionIndex)
+
+	add := func(pkg string, b *spec.Bundle) {
+		for _, gv := range b.Versions {
+			for i := range gv.Definitions {
+				def := &gv.Definitions[i]
+				index[defKey{pkg, gv.Group.String(), gv.Version, def.Name}] = indexedDefinition{typeContext{pkg, gv}, def}
+			}
+		}
+	}
+
+	add("", bundle)
+	for pkg, dep := range deps {
+		add(pkg, dep)
+	}
+
+	return index
+}
+
+func (x definitionIndex) resolve(ctx typeContext, target spec.ReferenceTarget) (defKey, *indexedDefinition) {
+	k := defKey{ctx.pkg, ctx.gv.Group.String(), ctx.gv.Version, target.Name}
+	if scope := target.Scope; scope != nil {
+		k = defKey{scope.Package, scope.Group.String(), scope.Version, target.Name}
+	}
+
+	if def, ok := x[k]; ok {
+		return k, &def
+	}
+
+	return k, nil
+}
+
+// The flavour of schemas to produce.
+type schemaDialect int
+
+const (
+	// Structural schemas of CustomResourceDefinitions: references are inlined
+	// and unions are left unchecked.
+	dialectStructural schemaDialect = iota
+	// OpenAPI v3.0 components.
+	dialectOpenAPI
+	// JSON Schema draft 2020-12.
+	dialectJSONSchema
+)
+
+// Converts kure types to schemas. Structural schemas inline every reference;
+// other dialects refer to named schemas, and collect the definitions to emit.
+type schemaConverter struct {
+	defs    definitionIndex
+	dialect schemaDialect
+	stack   []defKey
+
+	names   map[defKey]string
+	pending []defKey
+}
+
+func newSchemaConverter(defs definitionIndex, dialect schemaDialect) *schemaConverter {
+	return &schemaConverter{defs: defs, dialect: dialect, names: make(map[defKey]string)}
+}
+
+// The schema of a definition, with its description and validation rules.
+func (c *schemaConverter) definition(def *indexedDefinition) (*Schema, error) {
+	s, err := c.schema(def.typeContext, def.def.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.dialect != dialectStructural {
+		if res, ok := def.def.Value.Variant.(*spec.ResourceType); ok && def.pkg == "" {
+			s.XGroupVersionKind = []GroupVersionKind{resourceGVK(def.gv, res)}
+		}
+	}
+
+	s.Description = def.def.Description
+	s.XValidations = append(s.XValidations, def.def.Validations...)
+	return s, nil
+}
+
+// Name a referenced definition, queueing it to be emitted.
+func (c *schemaConverter) name(k defKey) string {
+	if name, ok := c.names[k]; ok {
+		return name
+	}
+
+	var parts []string
+	for _, part := range []string{k.pkg, strings.ReplaceAll(k.group, "/", "."), k.version, k.name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	name := strings.Join(parts, ".")
+	c.names[k] = name
+	c.pending = append(c.pending, k)
+	return name
+}
+
+// Convert every queued definition (and those they refer to in turn).
+func (c *schemaConverter) referenced(schemas map[string]*Schema) error {
+	for len(c.pending) > 0 {
+		k := c.pending[0]
+		c.pending = c.pending[1:]
+
+		def := c.defs[k]
+		s, err := c.definition(&def)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.names[k], err)
+		}
+		schemas[c.names[k]] = s
+	}
+
+	return nil
+}
+
+func (c *schemaConverter) schema(ctx typeContext, t spec.Type) (*Schema, error) {
+	switch v := t.Variant.(type) {
+	case *spec.StringType:
+		s := &Schema{
+			Type:      "string",
+			Format:    v.Format,
+			MinLength: v.MinLength,
+			MaxLength: v.MaxLength,
+			Pattern:   v.Pattern,
+		}
+		for _, value := range v.Enum {
+			s.Enum = append(s.Enum, value)
+		}
+		return s, nil
+
+	case *spec.IntegerType:
+		s := &Schema{
+			Type:       "integer",
+			Minimum:    intBound(v.Minimum),
+			Maximum:    intBound(v.Maximum),
+			MultipleOf: intBound(v.MultipleOf),
+		}
+		if v.Size != 0 {
+			s.Format = fmt.Sprintf("int%d", v.Size)
+		}
+		c.exclusiveBounds(s, v.ExclusiveMinimum, v.ExclusiveMaximum)
+		return s, nil
+
+	case *spec.FloatType:
+		s := &Schema{
+			Type:       "number",
+			Minimum:    v.Minimum,
+			Maximum:    v.Maximum,
+			MultipleOf: v.MultipleOf,
+		}
+		switch v.Size {
+		case 32:
+			s.Format = "float"
+		case 64:
+			s.Format = "double"
+		}
+		c.exclusiveBounds(s, v.ExclusiveMinimum, v.ExclusiveMaximum)
+		return s, nil
+
+	case *spec.BooleanType:
+		return &Schema{Type: "boolean"}, nil
+
+	case *spec.ObjectType:
+		s := &Schema{Type: "object"}
+		for _, parent := range v.Inherit {
+			ps, err := c.schema(ctx, parent)
+			if err != nil {
+				return nil, err
+			}
+			mergeProperties(s, ps)
+		}
+
+		if err := c.properties(ctx, s, v.Properties); err != nil {
+			return nil, err
+		}
+		return s, nil
+
+	case *spec.ResourceType:
+		s := &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"apiVersion": {Type: "string", Description: "APIVersion defines the versioned schema of this representation of an object."},
+				"kind":       {Type: "string", Description: "Kind is a string value representing the REST resource this object represents."},
+			},
+		}
+
+		if err := c.properties(ctx, s, v.Properties); err != nil {
+			return nil, err
+		}
+		if _, ok := s.Properties["metadata"]; ok && c.dialect == dialectStructural {
+			s.Properties["metadata"] = &Schema{Type: "object"}
+		}
+		return s, nil
+
+	case *spec.ArrayType:
+		items, err := c.schema(ctx, v.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		s := &Schema{
+			Type:         "array",
+			Items:        items,
+			MinItems:     v.MinItems,
+			MaxItems:     v.MaxItems,
+			UniqueItems:  v.UniqueItems,
+			XListType:    v.ListType,
+			XListMapKeys: v.ListMapKeys,
+		}
+		// CRDs have no strategic merge patches
+		if c.dialect != dialectStructural {
+			s.XPatchStrategy = v.PatchStrategy
+			s.XPatchMergeKey = v.PatchMergeKey
+		}
+		return s, nil
+
+	case *spec.MapType:
+		values, err := c.schema(ctx, v.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: values,
+			MinProperties:        v.MinProperties,
+			MaxProperties:        v.MaxProperties,
+			XMapType:             v.MapType,
+		}, nil
+
+	case *spec.OptionalType:
+		s, err := c.schema(ctx, v.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.dialect == dialectJSONSchema {
+			return &Schema{OneOf: []*Schema{s, {Type: "null"}}}, nil
+		}
+
+		s.Nullable = true
+		return s, nil
+
+	case *spec.UnionType:
+		if c.dialect == dialectStructural {
+			if isIntOrString(v) {
+				return &Schema{
+					AnyOf:        []*Schema{{Type: "integer"}, {Type: "string"}},
+					XIntOrString: true,
+				}, nil
+			}
+
+			// structural schemas cannot express other unions
+			return &Schema{XPreserveUnknownFields: true}, nil
+		}
+
+		s := &Schema{XIntOrString: isIntOrString(v)}
+		for _, value := range v.Values {
+			vs, err := c.schema(ctx, value)
+			if err != nil {
+				return nil, err
+			}
+			s.OneOf = append(s.OneOf, vs)
+		}
+		return s, nil
+
+	case *spec.ReferenceType:
+		s, err := c.reference(ctx, v.Target)
+		if err != nil || v.Constraints == nil {
+			return s, err
+		}
+
+		cs, err := c.schema(ctx, *v.Constraints)
+		if err != nil {
+			return nil, err
+		}
+		constrainSchema(s, cs)
+		return s, nil
+
+	case *spec.UnknownType:
+		return &Schema{XPreserveUnknownFields: true}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %T", t.Variant)
+}
+
+func (c *schemaConverter) reference(ctx typeContext, target spec.ReferenceTarget) (*Schema, error) {
+	k, def := c.defs.resolve(ctx, target)
+
+	if def == nil {
+		if known := kubernetesSchemas[target.String()]; known != nil {
+			return known(), nil
+		}
+
+		log.Printf("warning: unresolved reference to %s", target)
+		return &Schema{Type: "object", XPreserveUnknownFields: true}, nil
+	}
+
+	switch c.dialect {
+	case dialectOpenAPI:
+		// siblings of $ref (e.g. descriptions) are ignored in OpenAPI v3.0
+		return &Schema{AllOf: []*Schema{{Ref: "#/components/schemas/" + c.name(k)}}}, nil
+	case dialectJSONSchema:
+		return &Schema{Ref: "#/$defs/" + c.name(k)}, nil
+	}
+
+	for _, seen := range c.stack {
+		if seen == k {
+			log.Printf("warning: recursive reference to %s", target)
+			return &Schema{Type: "object", XPreserveUnknownFields: true}, nil
+		}
+	}
+
+	c.stack = append(c.stack, k)
+	defer func() { c.stack = c.stack[:len(c.stack)-1] }()
+
+	s, err := c.definition(def)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", target.Name, err)
+	}
+
+	return s, nil
+}
+
+// Add the constraints of cs (the schema of a reference's constraints) to s.
+func constrainSchema(s, cs *Schema) {
+	if cs.MinLength != nil {
+		s.MinLength = cs.MinLength
+	}
+	if cs.MaxLength != nil {
+		s.MaxLength = cs.MaxLength
+	}
+	if cs.Minimum != nil {
+		s.Minimum = cs.Minimum
+	}
+	if cs.Maximum != nil {
+		s.Maximum = cs.Maximum
+	}
+	if cs.MultipleOf != nil {
+		s.MultipleOf = cs.MultipleOf
+	}
+	if cs.ExclusiveMinimum != nil {
+		s.ExclusiveMinimum = cs.ExclusiveMinimum
+	}
+	if cs.ExclusiveMaximum != nil {
+		s.ExclusiveMaximum = cs.ExclusiveMaximum
+	}
+	if cs.MinItems != nil {
+		s.MinItems = cs.MinItems
+	}
+	if cs.MaxItems != nil {
+		s.MaxItems = cs.MaxItems
+	}
+	if cs.MinProperties != nil {
+		s.MinProperties = cs.MinProperties
+	}
+	if cs.MaxProperties != nil {
+		s.MaxProperties = cs.MaxProperties
+	}
+	if cs.Pattern != "" {
+		s.Pattern = cs.Pattern
+	}
+	if cs.UniqueItems {
+		s.UniqueItems = true
+	}
+}
+
+// JSON Schema replaces the minimum or maximum with an exclusive bound, where
+// OpenAPI v3.0 qualifies it with a boolean.
+func (c *schemaConverter) exclusiveBounds(s *Schema, min, max bool) {
+	if c.dialect != dialectJSONSchema {
+		if min {
+			s.ExclusiveMinimum = true
+		}
+		if max {
+			s.ExclusiveMaximum = true
+		}
+		return
+	}
+
+	if min && s.Minimum != nil {
+		s.ExclusiveMinimum, s.Minimum = *s.Minimum, nil
+	}
+	if max && s.Maximum != nil {
+		s.ExclusiveMaximum, s.Maximum = *s.Maximum, nil
+	}
+}
+
+func (c *schemaConverter) properties(ctx typeContext, s *Schema, props []spec.Property) error {
+	if s.Properties == nil && len(props) > 0 {
+		s.Properties = make(map[string]*Schema, len(props))
+	}
+
+	for _, prop := range props {
+		ps, err := c.schema(ctx, prop.Value)
+		if err != nil {
+			return fmt.Errorf("property %s: %w", prop.Name, err)
+		}
+
+		if prop.Description != "" {
+			ps.Description = prop.Description
+		}
+		ps.Default = prop.Default
+		ps.XValidations = append(ps.XValidations, prop.Validations...)
+
+		s.Properties[prop.Name] = ps
+		if prop.Required {
+			s.Required = append(s.Required, prop.Name)
+		}
+	}
+
+	return nil
+}
+
+func mergeProperties(s, parent *Schema) {
+	if len(parent.Properties) > 0 && s.Properties == nil {
+		s.Properties = make(map[string]*Schema, len(parent.Properties))
+	}
+
+	for name, ps := range parent.Properties {
+		s.Properties[name] = ps
+	}
+	s.Required = append(s.Required, parent.Required...)
+	s.XValidations = append(s.XValidations, parent.XValidations...)
+}
+
+func isIntOrString(u *spec.UnionType) bool {
+	if len(u.Values) != 2 {
+		return false
+	}
+
+	_, isInt := u.Values[0].Variant.(*spec.IntegerType)
+	_, isString := u.Values[1].Variant.(*spec.StringType)
+	return isInt && isString
+}
+
+func intBound(n *int64) *float64 {
+	if n == nil {
+		return nil
+	}
+
+	f := float64(*n)
+	return &f
+}
@@ -0,0 +1,103 @@
+package walk
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+const corev1 = "k8s.io/api/core/v1"
+
+// Subresources of builtin kinds which the API server serves without a
+// +genclient:method marker, indexed by <package path>.<Kind>. They are not
+// JSON resources: pods/log streams text, and the connect verb upgrades or
+// proxies the connection (exec, attach, portforward and proxy). This is the
+// only table of subresources; all others come from markers, and the status
+// and scale subresources have fields of their own in spec.Subresources.
+var builtinSubresources = map[string][]spec.Subresource{
+	corev1 + ".Pod": {
+		{Name: "log", Verb: "get"},
+		{Name: "exec", Verb: "connect"},
+		{Name: "attach", Verb: "connect"},
+		{Name: "portforward", Verb: "connect"},
+		{Name: "proxy", Verb: "connect"},
+	},
+	corev1 + ".Service": {
+		{Name: "proxy", Verb: "connect"},
+	},
+	corev1 + ".Node": {
+		{Name: "proxy", Verb: "connect"},
+	},
+}
+
+func (g *Generator) subresources(kind string, comment Comment) (sub spec.Subresources, err error) {
+	for _, value := range comment.MarkerArgs("kubebuilder:subresource:scale") {
+		args, err := scanMarkerArgs(value)
+		if err != nil {
+			return sub, fmt.Errorf("invalid subresource:scale marker: %w", err)
+		}
+
+		sub.Scale = &spec.ScaleSubresource{
+			SpecReplicasPath:   args["specpath"],
+			StatusReplicasPath: args["statuspath"],
+			LabelSelectorPath:  args["selectorpath"],
+		}
+	}
+
+	for _, value := range comment.MarkerValues("genclient:method") {
+		args, err := scanMarkerArgs("name=" + value)
+		if err != nil {
+			return sub, fmt.Errorf("invalid genclient:method marker: %w", err)
+		}
+
+		// status is inferred with the resource's verbs (see resourceMeta)
+		name := args["subresource"]
+		if name == "" || name == "status" {
+			continue
+		}
+
+		subresource := spec.Subresource{Name: name, Verb: args["verb"]}
+		if subresource.Input, err = g.subresourceKind(args["input"]); err != nil {
+			return sub, fmt.Errorf("genclient:method %s input: %w", args["name"], err)
+		}
+		if subresource.Output, err = g.subresourceKind(args["result"]); err != nil {
+			return sub, fmt.Errorf("genclient:method %s result: %w", args["name"], err)
+		}
+
+		sub.Additional = append(sub.Additional, subresource)
+
+		// getScale and updateScale methods imply the scale subresource
+		if name == "scale" && sub.Scale == nil {
+			sub.Scale = &spec.ScaleSubresource{}
+		}
+	}
+
+	sub.Additional = append(sub.Additional, builtinSubresources[g.Target.Path()+"."+kind]...)
+
+	return
+}
+
+// Resolve the input or result type of a genclient method, which is either a
+// type in the same package or a fully qualified path/to/package.Type. A type
+// in a package which is not declared is left out with a warning, rather than
+// failing the generation of the resource.
+func (g *Generator) subresourceKind(name string) (*spec.ReferenceTarget, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	pkgPath := g.Target.Path()
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		pkgPath, name = name[:i], name[i+1:]
+	}
+
+	scope, err := g.referenceScope(pkgPath)
+	if err != nil {
+		log.Printf("warning: genclient:method type %s.%s: %v", pkgPath, name, err)
+		return nil, nil
+	}
+
+	return &spec.ReferenceTarget{Scope: scope, Name: name}, nil
+}
@@ -0,0 +1,167 @@
+package walk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The lines 1 to n, with some replaced.
+func numberedLines(n int, replace map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if r, ok := replace[i]; ok {
+			b.WriteString(r + "\n")
+		} else {
+			fmt.Fprintf(&b, "%d\n", i)
+		}
+	}
+
+	return b.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		newPath  string
+		want     string
+	}{
+		{
+			name: "unchanged",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "separate hunks",
+			old:  numberedLines(20, nil),
+			new:  numberedLines(20, map[int]string{2: "two", 19: "nineteen"}),
+			want: `@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -16,5 +16,5 @@
+ 16
+ 17
+ 18
+-19
++nineteen
+ 20
+`,
+		},
+		{
+			name: "hunks split by one line more than twice the context",
+			old:  numberedLines(12, nil),
+			new:  numberedLines(12, map[int]string{2: "two", 10: "ten"}),
+			want: `@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -7,6 +7,6 @@
+ 7
+ 8
+ 9
+-10
++ten
+ 11
+ 12
+`,
+		},
+		{
+			name: "hunks merged by twice the context",
+			old:  numberedLines(12, nil),
+			new:  numberedLines(12, map[int]string{2: "two", 9: "nine"}),
+			want: `@@ -1,12 +1,12 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
++nine
+ 10
+ 11
+ 12
+`,
+		},
+		{
+			name: "insertion",
+			old:  "a\nb\nc\n",
+			new:  "a\nb\nx\ny\nc\n",
+			want: `@@ -1,3 +1,5 @@
+ a
+ b
++x
++y
+ c
+`,
+		},
+		{
+			name: "newline added at end of file",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: `@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+		},
+		{
+			name: "newline removed at end of file",
+			old:  "a\nb\n",
+			new:  "a\nc",
+			want: `@@ -1,2 +1,2 @@
+ a
+-b
++c
+\ No newline at end of file
+`,
+		},
+		{
+			name: "added file",
+			old:  "",
+			new:  "x\ny\n",
+			want: `@@ -0,0 +1,2 @@
++x
++y
+`,
+		},
+		{
+			name:    "deleted file",
+			old:     "x\n",
+			new:     "",
+			newPath: "/dev/null",
+			want: `@@ -1 +0,0 @@
+-x
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPath := tt.newPath
+			if newPath == "" {
+				newPath = "b/file"
+			}
+
+			got := unifiedDiff("a/file", newPath, []byte(tt.old), []byte(tt.new))
+			want := "--- a/file\n+++ " + newPath + "\n" + tt.want
+
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package walk
+
+import (
+	"fmt"
+	"go/doc"
+	"go/types"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+const validationMarker = "kubebuilder:validation:"
+
+// The kubebuilder:validation markers of constraints, and the variants of the
+// types they apply to.
+var constraintMarkers = map[string][]string{
+	"MinLength": {"string"},
+	"MaxLength": {"string"},
+	"Pattern":   {"string"},
+
+	"Minimum":          {"integer", "float"},
+	"Maximum":          {"integer", "float"},
+	"MultipleOf":       {"integer", "float"},
+	"ExclusiveMinimum": {"integer", "float"},
+	"ExclusiveMaximum": {"integer", "float"},
+
+	"MinItems":    {"array"},
+	"MaxItems":    {"array"},
+	"UniqueItems": {"array"},
+
+	"MinProperties": {"map"},
+	"MaxProperties": {"map"},
+}
+
+// The names of the constraint markers of a comment.
+func constraintMarkerNames(comment Comment) (names []string) {
+	for _, m := range comment.Markers {
+		rest, ok := strings.CutPrefix(m, validationMarker)
+		if !ok {
+			continue
+		}
+
+		name, _, _ := strings.Cut(rest, "=")
+		if _, ok := constraintMarkers[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	return
+}
+
+// Fill in the constraints given by kubebuilder:validation markers, returning
+// the names of those which do not apply to the variant of t.
+func applyConstraints(t *spec.Type, comment Comment) (misfits []string, err error) {
+	for _, name := range constraintMarkerNames(comment) {
+		if !slices.Contains(constraintMarkers[name], t.Variant.Variant()) {
+			misfits = append(misfits, name)
+		}
+	}
+
+	switch v := t.Variant.(type) {
+	case *spec.StringType:
+		if v.MinLength, err = intMarker(comment, "MinLength"); err != nil {
+			return
+		}
+		if v.MaxLength, err = intMarker(comment, "MaxLength"); err != nil {
+			return
+		}
+		if pattern := comment.Marker(validationMarker + "Pattern"); pattern != "" {
+			if v.Pattern, err = unquoteMarker(pattern); err != nil {
+				return nil, fmt.Errorf("invalid Pattern marker: %w", err)
+			}
+		}
+
+	case *spec.IntegerType:
+		if v.Minimum, err = intMarker(comment, "Minimum"); err != nil {
+			return
+		}
+		if v.Maximum, err = intMarker(comment, "Maximum"); err != nil {
+			return
+		}
+		if v.MultipleOf, err = intMarker(comment, "MultipleOf"); err != nil {
+			return
+		}
+		v.ExclusiveMinimum = comment.Marker(validationMarker+"ExclusiveMinimum") == "true"
+		v.ExclusiveMaximum = comment.Marker(validationMarker+"ExclusiveMaximum") == "true"
+
+	case *spec.FloatType:
+		if v.Minimum, err = floatMarker(comment, "Minimum"); err != nil {
+			return
+		}
+		if v.Maximum, err = floatMarker(comment, "Maximum"); err != nil {
+			return
+		}
+		if v.MultipleOf, err = floatMarker(comment, "MultipleOf"); err != nil {
+			return
+		}
+		v.ExclusiveMinimum = comment.Marker(validationMarker+"ExclusiveMinimum") == "true"
+		v.ExclusiveMaximum = comment.Marker(validationMarker+"ExclusiveMaximum") == "true"
+
+	case *spec.ArrayType:
+		if v.MinItems, err = intMarker(comment, "MinItems"); err != nil {
+			return
+		}
+		if v.MaxItems, err = intMarker(comment, "MaxItems"); err != nil {
+			return
+		}
+		v.UniqueItems = comment.Marker(validationMarker+"UniqueItems") == "true"
+
+	case *spec.MapType:
+		if v.MinProperties, err = intMarker(comment, "MinProperties"); err != nil {
+			return
+		}
+		if v.MaxProperties, err = intMarker(comment, "MaxProperties"); err != nil {
+			return
+		}
+	}
+
+	return misfits, nil
+}
+
+// Apply the constraint markers of a comment to the type r generated for t: to
+// r itself, or to the constraints of a reference. Markers which do not apply
+// are reported and ignored.
+func constrain(t types.Type, r *spec.Type, comment Comment) error {
+	names := constraintMarkerNames(comment)
+	if len(names) == 0 {
+		return nil
+	}
+
+	target := r
+	ref, isRef := r.Variant.(*spec.ReferenceType)
+	if isRef {
+		target = constraintType(t)
+	}
+
+	misfits := names
+	if target != nil {
+		var err error
+		if misfits, err = applyConstraints(target, comment); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range misfits {
+		log.Printf("warning: +%s%s does not apply to %s, ignored", validationMarker, name, t)
+	}
+	if isRef && target != nil && len(misfits) < len(names) {
+		ref.Constraints = target
+	}
+
+	return nil
+}
+
+// A type to hold the constraints on a use of the named type t, by the variant
+// its underlying type is generated as; nil if it has no constraints.
+func constraintType(t types.Type) *spec.Type {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch info := u.Info(); {
+		case info&types.IsString != 0:
+			return &spec.Type{Variant: &spec.StringType{}}
+		case info&types.IsInteger != 0:
+			return &spec.Type{Variant: &spec.IntegerType{}}
+		case info&types.IsFloat != 0:
+			return &spec.Type{Variant: &spec.FloatType{}}
+		}
+
+	case *types.Slice:
+		if et, ok := u.Elem().(*types.Basic); ok && et.Kind() == types.Byte {
+			return &spec.Type{Variant: &spec.StringType{}}
+		}
+		return &spec.Type{Variant: &spec.ArrayType{Values: spec.Type{Variant: &spec.UnknownType{}}}}
+
+	case *types.Map:
+		return &spec.Type{Variant: &spec.MapType{Values: spec.Type{Variant: &spec.UnknownType{}}}}
+	}
+
+	return nil
+}
+
+// The doc comment of the items of an array, with the markers of the array's
+// kubebuilder:validation:items: markers.
+func itemsDoc(d *doc.Type) *doc.Type {
+	comment := ReadComment(d.Doc)
+
+	var b strings.Builder
+	for _, m := range comment.Markers {
+		if rest, ok := strings.CutPrefix(m, validationMarker+"items:"); ok {
+			fmt.Fprintf(&b, "+%s%s\n", validationMarker, rest)
+		}
+	}
+
+	return &doc.Type{Doc: b.String()}
+}
+
+func intMarker(comment Comment, name string) (*int64, error) {
+	value := comment.Marker(validationMarker + name)
+	if value == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s marker: %w", name, err)
+	}
+
+	return &n, nil
+}
+
+func floatMarker(comment Comment, name string) (*float64, error) {
+	value := comment.Marker(validationMarker + name)
+	if value == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s marker: %w", name, err)
+	}
+
+	return &n, nil
+}
+
+// Strip the quotes (`raw` or "interpreted") from a marker value, if any.
+func unquoteMarker(value string) (string, error) {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '`') {
+		return strconv.Unquote(value)
+	}
+
+	return value, nil
+}
+
+// Read the CEL rules of all kubebuilder:validation:XValidation markers.
+func validationRules(comment Comment) ([]spec.ValidationRule, error) {
+	var rules []spec.ValidationRule
+
+	for _, value := range comment.MarkerArgs(validationMarker + "XValidation") {
+		args, err := scanMarkerArgs(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XValidation marker: %w", err)
+		}
+		if args["rule"] == "" {
+			return nil, fmt.Errorf("invalid XValidation marker: missing rule")
+		}
+
+		rules = append(rules, spec.ValidationRule{
+			Rule:              args["rule"],
+			Message:           args["message"],
+			MessageExpression: args["messageExpression"],
+			Reason:            args["reason"],
+			FieldPath:         args["fieldPath"],
+		})
+	}
+
+	return rules, nil
+}
+
+// Fill in the server-side apply semantics of lists and maps from the
+// +listType, +listMapKey and +mapType markers.
+func applyListSemantics(t *spec.Type, comment Comment) error {
+	switch v := nonOptional(t).Variant.(type) {
+	case *spec.ArrayType:
+		switch listType := comment.Marker("listType"); listType {
+		case "":
+		case spec.ListTypeAtomic, spec.ListTypeSet, spec.ListTypeMap:
+			v.ListType = listType
+		default:
+			return fmt.Errorf("invalid listType %q", listType)
+		}
+
+		v.ListMapKeys = comment.MarkerValues("listMapKey")
+		if v.ListType == spec.ListTypeMap && len(v.ListMapKeys) == 0 {
+			return fmt.Errorf("listType=map requires a listMapKey")
+		}
+
+	case *spec.MapType:
+		switch mapType := comment.Marker("mapType"); mapType {
+		case "":
+		case spec.MapTypeGranular, spec.MapTypeAtomic:
+			v.MapType = mapType
+		default:
+			return fmt.Errorf("invalid mapType %q", mapType)
+		}
+	}
+
+	return nil
+}
+
+// The type of a value which may be null (e.g. a +nullable list).
+func nonOptional(t *spec.Type) *spec.Type {
+	if v, ok := t.Variant.(*spec.OptionalType); ok {
+		return &v.Value
+	}
+
+	return t
+}
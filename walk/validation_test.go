@@ -0,0 +1,135 @@
+package walk
+
+import (
+	"go/doc"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+func markers(markers ...string) Comment {
+	return Comment{Markers: markers}
+}
+
+func int64p(n int64) *int64 {
+	return &n
+}
+
+func TestApplyConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       spec.TypeVariant
+		comment Comment
+		want    spec.TypeVariant
+		misfits []string
+	}{
+		{
+			name:    "string",
+			t:       &spec.StringType{},
+			comment: markers("kubebuilder:validation:MaxLength=4", "kubebuilder:validation:Pattern=`^a+$`"),
+			want:    &spec.StringType{MaxLength: int64p(4), Pattern: "^a+$"},
+		},
+		{
+			name:    "integer",
+			t:       &spec.IntegerType{},
+			comment: markers("kubebuilder:validation:Minimum=1", "kubebuilder:validation:ExclusiveMaximum=true", "kubebuilder:validation:Maximum=9"),
+			want:    &spec.IntegerType{Minimum: int64p(1), Maximum: int64p(9), ExclusiveMaximum: true},
+		},
+		{
+			name:    "array",
+			t:       &spec.ArrayType{},
+			comment: markers("kubebuilder:validation:MinItems=1", "kubebuilder:validation:UniqueItems=true"),
+			want:    &spec.ArrayType{MinItems: int64p(1), UniqueItems: true},
+		},
+		{
+			name:    "misfit",
+			t:       &spec.ArrayType{},
+			comment: markers("kubebuilder:validation:MaxLength=4", "kubebuilder:validation:MaxItems=2"),
+			want:    &spec.ArrayType{MaxItems: int64p(2)},
+			misfits: []string{"MaxLength"},
+		},
+		{
+			name:    "other markers",
+			t:       &spec.BooleanType{},
+			comment: markers("kubebuilder:validation:Optional", "listType=atomic"),
+			want:    &spec.BooleanType{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ := &spec.Type{Variant: tt.t}
+
+			misfits, err := applyConstraints(typ, tt.comment)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(typ.Variant, tt.want) {
+				t.Errorf("got %#v, want %#v", typ.Variant, tt.want)
+			}
+			if !reflect.DeepEqual(misfits, tt.misfits) {
+				t.Errorf("misfits %v, want %v", misfits, tt.misfits)
+			}
+		})
+	}
+}
+
+func TestApplyConstraintsInvalid(t *testing.T) {
+	typ := &spec.Type{Variant: &spec.StringType{}}
+
+	if _, err := applyConstraints(typ, markers("kubebuilder:validation:MaxLength=four")); err == nil {
+		t.Error("expected an error for a non-numeric MaxLength")
+	}
+}
+
+func TestConstrainReference(t *testing.T) {
+	mode := types.NewNamed(types.NewTypeName(token.NoPos, nil, "Mode", nil), types.Typ[types.String], nil)
+	ref := &spec.ReferenceType{Target: spec.ReferenceTarget{Name: "Mode"}}
+
+	if err := constrain(mode, &spec.Type{Variant: ref}, markers("kubebuilder:validation:MaxLength=4")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &spec.Type{Variant: &spec.StringType{MaxLength: int64p(4)}}
+	if !reflect.DeepEqual(ref.Constraints, want) {
+		t.Errorf("constraints %#v, want %#v", ref.Constraints, want)
+	}
+
+	// a struct has no constraints to hold
+	object := types.NewNamed(types.NewTypeName(token.NoPos, nil, "Spec", nil), types.NewStruct(nil, nil), nil)
+	ref = &spec.ReferenceType{Target: spec.ReferenceTarget{Name: "Spec"}}
+
+	if err := constrain(object, &spec.Type{Variant: ref}, markers("kubebuilder:validation:MaxLength=4")); err != nil {
+		t.Fatal(err)
+	}
+	if ref.Constraints != nil {
+		t.Errorf("constraints %#v on a struct", ref.Constraints)
+	}
+}
+
+func TestApplyListSemanticsNullable(t *testing.T) {
+	list := &spec.ArrayType{Values: spec.Type{Variant: &spec.ObjectType{}}}
+	typ := &spec.Type{Variant: &spec.OptionalType{Value: spec.Type{Variant: list}}}
+
+	if err := applyListSemantics(typ, markers("listType=map", "listMapKey=name")); err != nil {
+		t.Fatal(err)
+	}
+
+	if list := nonOptional(typ).Variant.(*spec.ArrayType); list.ListType != spec.ListTypeMap || !reflect.DeepEqual(list.ListMapKeys, []string{"name"}) {
+		t.Errorf("list type %q with keys %v, want a map keyed by name", list.ListType, list.ListMapKeys)
+	}
+}
+
+func TestItemsDoc(t *testing.T) {
+	d := &doc.Type{Doc: "Tags of the thing.\n+kubebuilder:validation:MaxItems=3\n+kubebuilder:validation:items:MaxLength=8\n"}
+
+	got := ReadComment(itemsDoc(d).Doc).Markers
+	want := []string{"kubebuilder:validation:MaxLength=8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package walk
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The verbs of a resource client, as named by client-gen.
+var standardVerbs = []string{
+	"create",
+	"update",
+	"updateStatus",
+	"delete",
+	"deleteCollection",
+	"get",
+	"list",
+	"watch",
+	"patch",
+	"apply",
+	"applyStatus",
+}
+
+// Find the verbs supported by a resource from its +genclient markers. Types
+// without +genclient (i.e., custom resources) support every standard verb.
+func resourceVerbs(comment Comment, status bool) ([]string, error) {
+	verbs := standardVerbs
+
+	if comment.Marker("genclient:noVerbs") == "true" {
+		return []string{}, nil
+	}
+
+	if only := comment.Marker("genclient:onlyVerbs"); only != "" {
+		var err error
+		if verbs, err = scanVerbs(only); err != nil {
+			return nil, fmt.Errorf("invalid genclient:onlyVerbs marker: %w", err)
+		}
+	}
+
+	var skip []string
+	if values := comment.Marker("genclient:skipVerbs"); values != "" {
+		var err error
+		if skip, err = scanVerbs(values); err != nil {
+			return nil, fmt.Errorf("invalid genclient:skipVerbs marker: %w", err)
+		}
+	}
+	if !status || comment.Marker("genclient:noStatus") == "true" {
+		skip = append(skip, "updateStatus", "applyStatus")
+	}
+
+	supported := make([]string, 0, len(verbs))
+	for _, verb := range verbs {
+		if !slices.Contains(skip, verb) {
+			supported = append(supported, verb)
+		}
+	}
+
+	return supported, nil
+}
+
+func scanVerbs(values string) ([]string, error) {
+	verbs := strings.Split(values, ",")
+
+	for i, verb := range verbs {
+		verb = strings.TrimSpace(verb)
+		if !slices.Contains(standardVerbs, verb) {
+			return nil, fmt.Errorf("unknown verb %q", verb)
+		}
+
+		verbs[i] = verb
+	}
+
+	return verbs, nil
+}
@@ -0,0 +1,124 @@
+package walk
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+// Compute the version of the API, as configured in kure.toml. If bumping, the
+// version of the previous bundle (if any) is incremented according to the
+// changes in the new bundle: major for breaking changes, minor for additions,
+// patch for documentation.
+func APIVersion(conf *config.Config, local *LocalGoModule, previous, bundle *spec.Bundle) (string, error) {
+	if conf.Version == nil {
+		return "", nil
+	}
+
+	version, err := baseVersion(conf.Version, local)
+	if err != nil {
+		return "", err
+	}
+
+	if conf.Version.Bump && previous != nil && previous.API.Version != "" {
+		prev, err := semver.NewVersion(previous.API.Version)
+		if err != nil {
+			return "", fmt.Errorf("invalid previous version %q: %w", previous.API.Version, err)
+		}
+
+		if next := bumpVersion(*prev, spec.Diff(previous, bundle)); next.GreaterThan(version) {
+			version = next
+		}
+	}
+
+	return version.String(), nil
+}
+
+func baseVersion(conf *config.Version, local *LocalGoModule) (*semver.Version, error) {
+	switch conf.Source {
+	case config.VersionSourceConfig:
+		return semver.NewVersion(conf.Base)
+	case config.VersionSourceGit:
+		return gitVersion()
+	case config.VersionSourceModule:
+		return moduleVersion(local), nil
+	case "":
+		if conf.Base != "" {
+			return semver.NewVersion(conf.Base)
+		}
+		if version, err := gitVersion(); err == nil {
+			return version, nil
+		}
+		return moduleVersion(local), nil
+	}
+
+	return nil, fmt.Errorf("unknown version source %q", conf.Source)
+}
+
+// Read the version from the latest git tag reachable from HEAD.
+func gitVersion() (*semver.Version, error) {
+	out, err := exec.Command("git", "describe", "--tags", "--abbrev=0").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git describe: %w", err)
+	}
+
+	tag := strings.TrimSpace(string(out))
+	version, err := semver.NewVersion(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version tag %q: %w", tag, err)
+	}
+
+	return version, nil
+}
+
+var majorSuffix = regexp.MustCompile(`/v(\d+)$`)
+
+// Take the major version from the /vN suffix of the module path. Only the
+// major version is known this way: modules without a suffix (v0 or v1, which
+// cannot be told apart) are version 0.0.0, and gopkg.in style .vN suffixes are
+// not recognised.
+func moduleVersion(local *LocalGoModule) *semver.Version {
+	major := "0"
+	if m := majorSuffix.FindStringSubmatch(local.Path); m != nil {
+		major = m[1]
+	}
+
+	return semver.MustParse(major + ".0.0")
+}
+
+// Increment the previous version according to the changes. Breaking changes
+// bump the major version even before 1.0.
+func bumpVersion(prev semver.Version, changes []spec.Change) *semver.Version {
+	var next semver.Version
+
+	switch {
+	case spec.Breaking(changes):
+		next = prev.IncMajor()
+	case hasChange(changes, spec.ChangeAdded, spec.ChangeRemoved, spec.ChangeChanged):
+		next = prev.IncMinor()
+	case hasChange(changes, spec.ChangeDescribed):
+		next = prev.IncPatch()
+	default:
+		next = prev
+	}
+
+	return &next
+}
+
+func hasChange(changes []spec.Change, kinds ...spec.ChangeKind) bool {
+	for _, c := range changes {
+		for _, kind := range kinds {
+			if c.Kind == kind {
+				return true
+			}
+		}
+	}
+
+	return false
+}
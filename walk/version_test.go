@@ -0,0 +1,97 @@
+package walk
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/kure-sh/ingest-go/config"
+	"github.com/kure-sh/ingest-go/spec"
+)
+
+func TestBumpVersion(t *testing.T) {
+	breaking := spec.Change{Kind: spec.ChangeRemoved, Breaking: true}
+	added := spec.Change{Kind: spec.ChangeAdded}
+	removed := spec.Change{Kind: spec.ChangeRemoved}
+	changed := spec.Change{Kind: spec.ChangeChanged}
+	described := spec.Change{Kind: spec.ChangeDescribed}
+
+	tests := []struct {
+		prev    string
+		changes []spec.Change
+		want    string
+	}{
+		{"1.2.3", nil, "1.2.3"},
+		{"1.2.3", []spec.Change{described}, "1.2.4"},
+		{"1.2.3", []spec.Change{described, added}, "1.3.0"},
+		{"1.2.3", []spec.Change{removed}, "1.3.0"},
+		{"1.2.3", []spec.Change{changed}, "1.3.0"},
+		{"1.2.3", []spec.Change{added, breaking}, "2.0.0"},
+		{"0.4.1", []spec.Change{breaking}, "1.0.0"},
+		{"0.4.1", []spec.Change{added}, "0.5.0"},
+		{"0.4.1", []spec.Change{described}, "0.4.2"},
+	}
+
+	for _, tt := range tests {
+		got := bumpVersion(*semver.MustParse(tt.prev), tt.changes)
+		if got.String() != tt.want {
+			t.Errorf("%s with %v: got %s, want %s", tt.prev, tt.changes, got, tt.want)
+		}
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	previous := &spec.Bundle{
+		API: spec.API{Version: "1.4.0"},
+		Versions: []*spec.APIGroupVersion{
+			{Group: spec.APIGroupIdentifier{Name: "apps"}, Version: "v1beta1"},
+			{Group: spec.APIGroupIdentifier{Name: "apps"}, Version: "v1"},
+		},
+	}
+	bundle := &spec.Bundle{
+		Versions: []*spec.APIGroupVersion{
+			{Group: spec.APIGroupIdentifier{Name: "apps"}, Version: "v1"},
+		},
+	}
+	local := &LocalGoModule{Path: "example.com/widgets/v3"}
+
+	tests := []struct {
+		name     string
+		version  *config.Version
+		previous *spec.Bundle
+		want     string
+	}{
+		{"unversioned", nil, previous, ""},
+		{"base", &config.Version{Source: config.VersionSourceConfig, Base: "1.2.0"}, previous, "1.2.0"},
+		{"module", &config.Version{Source: config.VersionSourceModule}, previous, "3.0.0"},
+		{"bumped", &config.Version{Base: "1.2.0", Bump: true}, previous, "2.0.0"},
+		{"base above bump", &config.Version{Base: "3.1.0", Bump: true}, previous, "3.1.0"},
+		{"no previous", &config.Version{Base: "1.2.0", Bump: true}, nil, "1.2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := APIVersion(&config.Config{Version: tt.version}, local, tt.previous, bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleVersion(t *testing.T) {
+	tests := map[string]string{
+		"example.com/widgets/v3": "3.0.0",
+		"example.com/widgets":    "0.0.0",
+		"gopkg.in/widgets.v2":    "0.0.0",
+	}
+
+	for path, want := range tests {
+		if got := moduleVersion(&LocalGoModule{Path: path}); got.String() != want {
+			t.Errorf("%s: got %s, want %s", path, got, want)
+		}
+	}
+}